@@ -5,4 +5,6 @@ go 1.13
 require (
 	github.com/32bitkid/bitreader v1.0.1
 	github.com/lucasb-eyer/go-colorful v1.0.3
+	golang.org/x/image v0.0.0-20200927104501-e162460cd6b5
+	golang.org/x/text v0.3.3
 )
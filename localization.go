@@ -0,0 +1,512 @@
+package sci
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/encoding"
+
+	"github.com/32bitkid/sci/resource"
+)
+
+// scriptContext is the context of a message from a strings block of a script.
+func scriptContext(n resource.Number, offset int) string {
+	return fmt.Sprintf("script.%03d@0x%04x", n, offset)
+}
+
+// Scripts loads every script resource in the Root, by number.
+func (root *Root) Scripts() (map[resource.Number]*resource.Script, error) {
+	scripts := make(map[resource.Number]*resource.Script)
+	for _, m := range root.Mapping {
+		if m.Type() != resource.TypeScript {
+			continue
+		}
+		res, err := m.Resource()
+		if err != nil {
+			return nil, fmt.Errorf("script %d: %w", m.Number(), err)
+		}
+		// the blocks are copied, so that patching does not change the resource
+		script, err := resource.NewScript(append([]byte(nil), res.Bytes()...))
+		if err != nil {
+			return nil, fmt.Errorf("script %d: %w", m.Number(), err)
+		}
+		scripts[m.Number()] = script
+	}
+	return scripts, nil
+}
+
+func sortedScripts(scripts map[resource.Number]*resource.Script) []resource.Number {
+	numbers := make([]resource.Number, 0, len(scripts))
+	for n := range scripts {
+		numbers = append(numbers, n)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
+// stringRef is an instruction that loads the address of a string or a said
+// spec.
+type stringRef struct {
+	resource.ScriptInstruction
+	// relative is set when the operand is relative to the next instruction.
+	relative bool
+}
+
+// stringRefs finds the lofsa and lofss instructions of a script, by the
+// offset of the string or said spec that they load. SCI0 scripts use offsets relative to
+// the next instruction, but absolute offsets are also recognized.
+func stringRefs(script *resource.Script, strs map[int]bool) map[int][]stringRef {
+	refs := make(map[int][]stringRef)
+	for _, block := range script.Blocks {
+		instructions, _ := block.Instructions()
+		for _, in := range instructions {
+			if in.Op() != resource.ScriptOpLofsa && in.Op() != resource.ScriptOpLofss {
+				continue
+			}
+			if target := in.Offset + in.Size + in.Operands[0]; strs[target] {
+				refs[target] = append(refs[target], stringRef{in, true})
+			} else if target := in.Operands[0] & 0xFFFF; strs[target] {
+				refs[target] = append(refs[target], stringRef{in, false})
+			}
+		}
+	}
+	return refs
+}
+
+// TextCall is a procedure, or a kernel function, whose first two arguments
+// may be the number and index of a string of a text resource.
+type TextCall struct {
+	// Kernel is set for a kernel function, numbered by Export. Procedures of
+	// script 0 are called with callb, and others with calle.
+	Kernel bool
+	Script resource.Number
+	Export int
+}
+
+// TextCalls are the calls that LocalizationCatalog looks for: Print, which is
+// export 0 of script 255, and the Display kernel function. Games that print
+// with procedures of their own can add them.
+var TextCalls = []TextCall{
+	{Script: 255, Export: 0},
+	{Kernel: true, Export: 0x1B},
+}
+
+// textRef is one of the TextCalls that was passed the number and index of a
+// string of a text resource, such as (Print 100 3).
+type textRef struct {
+	script resource.Number
+	offset int
+}
+
+// Script operations that push a value that textRefs does not know.
+const (
+	scriptOpPush     = 0x1B
+	scriptOpDup      = 0x1E
+	scriptOpPushSelf = 0x3E
+)
+
+// pushesUnknown reports whether an operation pushes a value that is only known
+// when the script runs: the accumulator, a copy of the top of the stack, an
+// address, self, or a variable.
+func pushesUnknown(op uint8) bool {
+	switch op {
+	case scriptOpPush, scriptOpDup, resource.ScriptOpLofss, scriptOpPushSelf:
+		return true
+	}
+	// loads, increments and decrements of variables that go to the stack
+	return op >= 0x40 && op&0x04 != 0 && op&0x30 != 0x10
+}
+
+// textRefs finds the TextCalls of a script whose first two arguments name a
+// string of one of the text resources. The arguments are only known when they
+// are pushed as immediate values, so texts that are chosen when the script
+// runs, or passed to other calls, are not found.
+func textRefs(n resource.Number, script *resource.Script, texts map[resource.Number]resource.Text) map[string][]textRef {
+	const unknown = -1
+
+	calls := make(map[TextCall]bool, len(TextCalls))
+	for _, c := range TextCalls {
+		calls[c] = true
+	}
+
+	refs := make(map[string][]textRef)
+	for _, block := range script.Blocks {
+		instructions, _ := block.Instructions()
+
+		// the values that were pushed since the last call
+		var pushed []int
+		for _, in := range instructions {
+			var (
+				call  TextCall
+				frame int
+			)
+			switch op := in.Op(); {
+			case op == resource.ScriptOpPushI:
+				pushed = append(pushed, in.Operands[0])
+				continue
+			case op == resource.ScriptOpPush0, op == resource.ScriptOpPush1, op == resource.ScriptOpPush2:
+				pushed = append(pushed, int(op-resource.ScriptOpPush0))
+				continue
+			case pushesUnknown(op):
+				pushed = append(pushed, unknown)
+				continue
+			case op == resource.ScriptOpCallE:
+				call, frame = TextCall{Script: resource.Number(in.Operands[0]), Export: in.Operands[1]}, in.Operands[2]
+			case op == resource.ScriptOpCallB:
+				call, frame = TextCall{Script: 0, Export: in.Operands[0]}, in.Operands[1]
+			case op == resource.ScriptOpCallK:
+				call, frame = TextCall{Kernel: true, Export: in.Operands[0]}, in.Operands[1]
+			default:
+				pushed = pushed[:0]
+				continue
+			}
+
+			// the arguments are the last values pushed, after their count
+			if args := frame / 2; calls[call] && args >= 2 && len(pushed) >= args {
+				number, index := pushed[len(pushed)-args], pushed[len(pushed)-args+1]
+				if text, ok := texts[resource.Number(number)]; number != unknown && ok && index >= 0 && index < len(text) {
+					ctx := textContext(resource.Number(number), index)
+					refs[ctx] = append(refs[ctx], textRef{n, in.Offset})
+				}
+			}
+			pushed = pushed[:0]
+		}
+	}
+	return refs
+}
+
+// Vocabulary loads the parser's vocabulary, vocab.000. It is nil when the
+// game has no parser.
+func (root *Root) Vocabulary() (*resource.Vocabulary, error) {
+	for _, m := range root.Mapping {
+		if m.Type() != resource.TypeVocab || m.Number() != 0 {
+			continue
+		}
+		res, err := m.Resource()
+		if err != nil {
+			return nil, fmt.Errorf("vocab 0: %w", err)
+		}
+		vocab, err := resource.NewVocabulary(res.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("vocab 0: %w", err)
+		}
+		return vocab, nil
+	}
+	return nil, nil
+}
+
+// saidComment explains how to translate a said spec.
+const saidComment = "said spec: the words must be in the parser's vocabulary, joined by , & / ( ) [ ] # < >"
+
+// LocalizationCatalog collects every string of the game that a player may
+// see or type: the strings of text resources, the strings blocks of scripts,
+// and the said specs that the parser matches input against. Text strings that
+// are passed to one of the TextCalls, such as (Print 100 3), are tagged with
+// the location of each call. Script strings that no code loads are tagged too,
+// as they are often the names of objects. Said specs are written with the
+// words of the parser's vocabulary, and are only collected when the game has
+// one.
+//
+// The strings are converted from the DOS code page enc, such as
+// charmap.CodePage437.
+func (root *Root) LocalizationCatalog(enc encoding.Encoding) (Catalog, error) {
+	catalog, err := root.TextCatalog(enc)
+	if err != nil {
+		return nil, err
+	}
+	texts, err := root.Texts()
+	if err != nil {
+		return nil, err
+	}
+	scripts, err := root.Scripts()
+	if err != nil {
+		return nil, err
+	}
+	vocab, err := root.Vocabulary()
+	if err != nil {
+		return nil, err
+	}
+
+	dec := enc.NewDecoder()
+	used := make(map[string][]textRef)
+	for _, n := range sortedScripts(scripts) {
+		script := scripts[n]
+		for ctx, refs := range textRefs(n, script, texts) {
+			used[ctx] = append(used[ctx], refs...)
+		}
+
+		strs := make(map[int]bool)
+		for _, block := range script.Blocks {
+			for _, s := range block.Strings() {
+				strs[s.Offset] = true
+			}
+		}
+		loaded := stringRefs(script, strs)
+
+		for _, block := range script.Blocks {
+			for _, s := range block.Strings() {
+				value, err := dec.String(s.Value)
+				if err != nil {
+					return nil, fmt.Errorf("script %d: %w", n, err)
+				}
+				m := Message{Context: scriptContext(n, s.Offset), Source: value}
+				if len(loaded[s.Offset]) == 0 {
+					m.Comment = "not loaded by any code, may be the name of an object"
+				}
+				catalog = append(catalog, m)
+			}
+			if vocab == nil {
+				continue
+			}
+			for _, said := range block.Saids() {
+				value, err := vocab.DecodeSaid(said.Data)
+				if err != nil {
+					return nil, fmt.Errorf("script %d: said spec at 0x%04x: %w", n, said.Offset, err)
+				}
+				catalog = append(catalog, Message{Context: scriptContext(n, said.Offset), Source: value, Comment: saidComment})
+			}
+		}
+	}
+
+	for i, m := range catalog {
+		refs := used[m.Context]
+		if len(refs) == 0 {
+			continue
+		}
+		lines := make([]string, len(refs))
+		for j, ref := range refs {
+			lines[j] = fmt.Sprintf("used by script.%03d at 0x%04x", ref.script, ref.offset)
+		}
+		catalog[i].Comment = strings.Join(lines, "\n")
+	}
+
+	return catalog, nil
+}
+
+// Patch is a replacement for a resource, see WritePatch.
+type Patch struct {
+	Type   resource.Type
+	Number resource.Number
+	Data   []byte
+}
+
+// WritePatches writes each patch as a patch file in the Root folder.
+func (root *Root) WritePatches(patches []Patch) error {
+	for _, p := range patches {
+		if err := root.WritePatch(p.Type, p.Number, p.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Localize applies the translations of a catalog that was written by
+// LocalizationCatalog, and returns a patch for each text and script resource
+// that changed.
+//
+// A translated script string that fits in the space of the original is
+// written in its place. Longer strings are moved to a new strings block at the
+// end of the script, and the code and relocated pointers that refer to them
+// are updated.
+func (root *Root) Localize(catalog Catalog, enc encoding.Encoding) ([]Patch, error) {
+	var patches []Patch
+
+	texts, err := root.TranslateTexts(catalog, enc)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range sortedNumbers(texts) {
+		b, err := texts[n].Encode()
+		if err != nil {
+			return nil, fmt.Errorf("text %d: %w", n, err)
+		}
+		patches = append(patches, Patch{Type: resource.TypeText, Number: n, Data: b})
+	}
+
+	scripts, err := root.Scripts()
+	if err != nil {
+		return nil, err
+	}
+	vocab, err := root.Vocabulary()
+	if err != nil {
+		return nil, err
+	}
+	translations := catalog.Translations()
+	encoder := enc.NewEncoder()
+	for _, n := range sortedScripts(scripts) {
+		script := scripts[n]
+		changed := map[int][]byte{}
+		for _, block := range script.Blocks {
+			for _, s := range block.Strings() {
+				t, ok := translations[scriptContext(n, s.Offset)]
+				if !ok {
+					continue
+				}
+				encoded, err := encoder.String(t)
+				if err != nil {
+					return nil, fmt.Errorf("script %d: %w", n, err)
+				}
+				changed[s.Offset] = append([]byte(encoded), 0x00)
+			}
+			for _, said := range block.Saids() {
+				t, ok := translations[scriptContext(n, said.Offset)]
+				if !ok || vocab == nil {
+					continue
+				}
+				if changed[said.Offset], err = vocab.EncodeSaid(t); err != nil {
+					return nil, fmt.Errorf("script %d: said spec at 0x%04x: %w", n, said.Offset, err)
+				}
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		b, err := patchScript(script, changed)
+		if err != nil {
+			return nil, fmt.Errorf("script %d: %w", n, err)
+		}
+		patches = append(patches, Patch{Type: resource.TypeScript, Number: n, Data: b})
+	}
+
+	return patches, nil
+}
+
+// scriptWords gives access to the words of a script, by their offset.
+type scriptWords struct{ *resource.Script }
+
+func (s scriptWords) at(offset int) []byte {
+	for _, block := range s.Blocks {
+		start := block.Offset + 4
+		if offset >= start && offset+2 <= start+len(block.Data) {
+			return block.Data[offset-start:]
+		}
+	}
+	return nil
+}
+
+// scriptItem is a string or a said spec of a script.
+type scriptItem struct {
+	block resource.ScriptBlockType
+	// size includes the terminator.
+	size int
+}
+
+func scriptItems(script *resource.Script) map[int]scriptItem {
+	items := make(map[int]scriptItem)
+	for _, block := range script.Blocks {
+		for _, s := range block.Strings() {
+			items[s.Offset] = scriptItem{resource.ScriptBlockStrings, len(s.Value) + 1}
+		}
+		for _, said := range block.Saids() {
+			items[said.Offset] = scriptItem{resource.ScriptBlockSaid, len(said.Data) + 1}
+		}
+	}
+	return items
+}
+
+// padding fills the space that is left by a shorter item. Said blocks are
+// padded with terminators, which read as empty said specs.
+func padding(t resource.ScriptBlockType) byte {
+	if t == resource.ScriptBlockSaid {
+		return 0xFF
+	}
+	return 0x00
+}
+
+// patchScript replaces strings and said specs of a script, by their offset.
+// Each replacement includes its terminator. Replacements that fit are written
+// in place, and the others are moved to new blocks at the end of the script.
+func patchScript(script *resource.Script, changed map[int][]byte) ([]byte, error) {
+	items := scriptItems(script)
+	targets := make(map[int]bool, len(items))
+	for offset := range items {
+		targets[offset] = true
+	}
+
+	var moved []int
+	for offset, b := range changed {
+		item := items[offset]
+		if len(b) > item.size {
+			moved = append(moved, offset)
+			continue
+		}
+		data := scriptWords{script}.at(offset)
+		copy(data, b)
+		for i := len(b); i < item.size; i++ {
+			data[i] = padding(item.block)
+		}
+	}
+	if len(moved) == 0 {
+		return script.Encode()
+	}
+	sort.Ints(moved)
+
+	end := 0
+	if n := len(script.Blocks); n > 0 {
+		last := script.Blocks[n-1]
+		end = last.Offset + 4 + len(last.Data)
+	}
+	var blocks []resource.ScriptBlock
+	destinations := make(map[int]int)
+	for _, t := range []resource.ScriptBlockType{resource.ScriptBlockStrings, resource.ScriptBlockSaid} {
+		block := resource.ScriptBlock{Type: t, Offset: end}
+		for _, offset := range moved {
+			if items[offset].block != t {
+				continue
+			}
+			destinations[offset] = end + 4 + len(block.Data)
+			block.Data = append(block.Data, changed[offset]...)
+		}
+		if len(block.Data) == 0 {
+			continue
+		}
+		if len(block.Data)%2 == 1 {
+			block.Data = append(block.Data, padding(t))
+		}
+		end += 4 + len(block.Data)
+		blocks = append(blocks, block)
+	}
+	if end > 0xFFFF {
+		return nil, fmt.Errorf("script is too large")
+	}
+
+	words := scriptWords{script}
+	for old, refs := range stringRefs(script, targets) {
+		dst, ok := destinations[old]
+		if !ok {
+			continue
+		}
+		for _, ref := range refs {
+			if !ref.Wide() {
+				return nil, fmt.Errorf("0x%04x is loaded by a short instruction at 0x%04x", old, ref.Offset)
+			}
+			value := dst
+			if ref.relative {
+				value = dst - (ref.Offset + ref.Size)
+			}
+			binary.LittleEndian.PutUint16(words.at(ref.OperandOffsets[0]), uint16(value))
+		}
+	}
+
+	for _, b := range script.Blocks {
+		if b.Type != resource.ScriptBlockRelocation || len(b.Data) < 2 {
+			continue
+		}
+		count := int(binary.LittleEndian.Uint16(b.Data))
+		for i := 0; i < count && 2+2*i+2 <= len(b.Data); i++ {
+			word := words.at(int(binary.LittleEndian.Uint16(b.Data[2+2*i:])))
+			if word == nil {
+				continue
+			}
+			if dst, ok := destinations[int(binary.LittleEndian.Uint16(word))]; ok {
+				binary.LittleEndian.PutUint16(word, uint16(dst))
+			}
+		}
+	}
+
+	script.Blocks = append(script.Blocks, blocks...)
+	return script.Encode()
+}
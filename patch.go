@@ -0,0 +1,17 @@
+package sci
+
+import (
+	"io/ioutil"
+	"path"
+
+	"github.com/32bitkid/sci/resource"
+)
+
+// WritePatch writes b as a patch file in the Root folder. The interpreter
+// loads a patch file in place of the resource of the same type and number.
+func (root *Root) WritePatch(t resource.Type, n resource.Number, b []byte) error {
+	patch := make([]byte, 0, 2+len(b))
+	patch = append(patch, 0x80|uint8(t), 0x00)
+	patch = append(patch, b...)
+	return ioutil.WriteFile(path.Join(root.Path, t.PatchName(n)), patch, 0644)
+}
@@ -13,7 +13,7 @@ type SCI struct {
 func (sci *SCI) pop() uint16 {
 	sci.SP -= 2
 	base := 0xFFFF - sci.SP
-	val := uint16(sci.Heap[base-1]<<8) | uint16(sci.Heap[base-2])
+	val := uint16(sci.Heap[base-1])<<8 | uint16(sci.Heap[base-2])
 	return val
 }
 
@@ -4,6 +4,22 @@ import (
 	"testing"
 )
 
+func TestPushPop(t *testing.T) {
+	var sci SCI
+	values := []uint16{0x1234, 0x8001, 0xFFFF, 0x00FF}
+	for _, v := range values {
+		sci.push(v)
+	}
+	for i := len(values) - 1; i >= 0; i-- {
+		if v := sci.pop(); v != values[i] {
+			t.Errorf("got 0x%04x, expected 0x%04x", v, values[i])
+		}
+	}
+	if sci.SP != 0 {
+		t.Errorf("got SP %d, expected 0", sci.SP)
+	}
+}
+
 func TestNotOp(t *testing.T) {
 	sci := SCI{
 		IP:  0x0000,
@@ -0,0 +1,320 @@
+package pmachine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/32bitkid/sci/resource"
+)
+
+// SaveSlot is an entry of the save directory, <game>SG.DIR, which holds the
+// descriptions of the saved games. The game of each slot is in the file
+// <game>SG.<nnn>, numbered by the slot.
+type SaveSlot struct {
+	Number      uint16
+	Description string
+}
+
+// saveDirectoryEnd follows the last slot of the save directory.
+const saveDirectoryEnd = 0xFFFF
+
+// ReadSaveDirectory parses a save directory. Each slot is its number, as a
+// little-endian word, followed by its NUL-terminated description.
+func ReadSaveDirectory(r io.Reader) ([]SaveSlot, error) {
+	br := bufio.NewReader(r)
+	var slots []SaveSlot
+	for {
+		var number uint16
+		if err := binary.Read(br, binary.LittleEndian, &number); err != nil {
+			return nil, fmt.Errorf("invalid save directory: %w", err)
+		}
+		if number == saveDirectoryEnd {
+			return slots, nil
+		}
+		s, err := br.ReadString(0x00)
+		if err != nil {
+			return nil, fmt.Errorf("slot %d: invalid description: %w", number, err)
+		}
+		slots = append(slots, SaveSlot{Number: number, Description: s[:len(s)-1]})
+	}
+}
+
+// WriteSaveDirectory writes a save directory in the form that
+// ReadSaveDirectory parses.
+func WriteSaveDirectory(w io.Writer, slots []SaveSlot) error {
+	var b bytes.Buffer
+	for _, slot := range slots {
+		if slot.Number == saveDirectoryEnd {
+			return fmt.Errorf("invalid slot number %d", slot.Number)
+		}
+		binary.Write(&b, binary.LittleEndian, slot.Number)
+		b.WriteString(slot.Description)
+		b.WriteByte(0x00)
+	}
+	binary.Write(&b, binary.LittleEndian, uint16(saveDirectoryEnd))
+	_, err := b.WriteTo(w)
+	return err
+}
+
+// SaveGame is a game saved by the interpreter. The file holds the
+// NUL-terminated version of the interpreter, which is checked before the game
+// is restored, followed by an image of the heap. The description of the save
+// is kept in the save directory.
+type SaveGame struct {
+	Version string
+	Heap    resource.Memory
+}
+
+// ReadSaveGame parses a saved game.
+func ReadSaveGame(r io.Reader) (*SaveGame, error) {
+	br := bufio.NewReader(r)
+
+	version, err := br.ReadString(0x00)
+	if err != nil {
+		return nil, fmt.Errorf("invalid save game version: %w", err)
+	}
+
+	b, err := ioutil.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	heap, err := resource.NewMemory(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SaveGame{Version: version[:len(version)-1], Heap: heap}, nil
+}
+
+// WriteTo writes the saved game in the same form that ReadSaveGame parses.
+func (s *SaveGame) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	b.WriteString(s.Version)
+	b.WriteByte(0x00)
+	b.Write(s.Heap)
+	return b.WriteTo(w)
+}
+
+// Restore loads the saved game into the machine, the way that the
+// interpreter resumes after RestoreGame. The registers and the stack are not
+// saved: the heap is copied, the stack is emptied, and the game continues
+// from the replay method of the game object, which is export 0 of script 0.
+// The replay selector is found in the game's selector vocabulary.
+func (s *SaveGame) Restore(sci *SCI, loaded []LoadedScript, replay uint16) error {
+	var main *LoadedScript
+	for i := range loaded {
+		if loaded[i].Number == 0 {
+			main = &loaded[i]
+		}
+	}
+	if main == nil {
+		return errors.New("script 0 is not loaded")
+	}
+	game, ok := main.Export(s.Heap, 0)
+	if !ok {
+		return errors.New("script 0 has no game object")
+	}
+	method, ok := s.Method(loaded, game, replay)
+	if !ok {
+		return fmt.Errorf("game object at %04x has no replay method", game)
+	}
+
+	sci.Heap = [0xFFFF]uint8{}
+	copy(sci.Heap[:], s.Heap)
+	sci.Acc, sci.SP, sci.IP = 0, 0, method
+	return nil
+}
+
+// LoadedScript is a script that was found in the heap.
+type LoadedScript struct {
+	Number  resource.Number
+	Address uint16
+	*resource.Script
+}
+
+// FindScripts locates each of the scripts in the heap, by searching for the
+// first of its blocks that does not change when the script is loaded. Scripts
+// that are not found were not loaded when the game was saved.
+func (s *SaveGame) FindScripts(scripts map[resource.Number]*resource.Script) []LoadedScript {
+	var loaded []LoadedScript
+	for n, script := range scripts {
+		if address, ok := findScript(s.Heap, script); ok {
+			loaded = append(loaded, LoadedScript{Number: n, Address: address, Script: script})
+		}
+	}
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].Number < loaded[j].Number })
+	return loaded
+}
+
+// findScript searches for a code or strings block of the script. Those blocks
+// have no relocated pointers, so they are identical in the heap.
+func findScript(heap []byte, script *resource.Script) (uint16, bool) {
+	for _, block := range script.Blocks {
+		if block.Type != resource.ScriptBlockCode && block.Type != resource.ScriptBlockStrings {
+			continue
+		}
+		if len(block.Data) < 8 {
+			continue
+		}
+		i := bytes.Index(heap, block.Data)
+		if i < 0 {
+			return 0, false
+		}
+		address := i - (block.Offset + 4)
+		if address < 0 || address > 0xFFFF {
+			return 0, false
+		}
+		return uint16(address), true
+	}
+	return 0, false
+}
+
+// Export returns the address of an entry of the exports of the script.
+func (l LoadedScript) Export(heap resource.Memory, i int) (uint16, bool) {
+	for _, block := range l.Blocks {
+		if block.Type != resource.ScriptBlockExports {
+			continue
+		}
+		base := l.Address + uint16(block.Offset+4)
+		if i < 0 || i >= int(heap.Word(base)) {
+			return 0, false
+		}
+		return l.Address + heap.Word(base+uint16(2+2*i)), true
+	}
+	return 0, false
+}
+
+// size returns the length of the script in the heap.
+func (l LoadedScript) size() int {
+	if len(l.Blocks) == 0 {
+		return 0
+	}
+	last := l.Blocks[len(l.Blocks)-1]
+	return last.Offset + 4 + len(last.Data) + 2
+}
+
+// Method finds the code of the method of an object for a selector. Methods
+// that the object does not define are searched for in its super classes,
+// which must be in the loaded scripts.
+func (s *SaveGame) Method(loaded []LoadedScript, obj uint16, selector uint16) (uint16, bool) {
+	// classes can only be nested so deeply, so a cycle is not followed forever
+	for depth := 0; depth < 0x100; depth++ {
+		script, ok := scriptAt(loaded, obj)
+		if !ok || s.Heap.Word(obj-8) != objectMagic {
+			return 0, false
+		}
+
+		// the selectors of the methods, then 0, then the offsets of their code
+		methods := obj + s.Heap.Word(obj-4)
+		count := s.Heap.Word(methods - 2)
+		for i := uint16(0); i < count; i++ {
+			if s.Heap.Word(methods+2*i) == selector {
+				return script.Address + s.Heap.Word(methods+2*(count+1+i)), true
+			}
+		}
+
+		super := s.Heap.Word(obj + 2)
+		if obj, ok = findClass(loaded, s.Heap, super); !ok {
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// scriptAt returns the loaded script that holds an address.
+func scriptAt(loaded []LoadedScript, address uint16) (LoadedScript, bool) {
+	for _, l := range loaded {
+		if int(address) >= int(l.Address) && int(address) < int(l.Address)+l.size() {
+			return l, true
+		}
+	}
+	return LoadedScript{}, false
+}
+
+// findClass returns the address of the class with a species.
+func findClass(loaded []LoadedScript, heap resource.Memory, species uint16) (uint16, bool) {
+	for _, l := range loaded {
+		for _, block := range l.Blocks {
+			if block.Type != resource.ScriptBlockClass {
+				continue
+			}
+			obj := l.Address + uint16(block.Offset+4) + 8
+			if heap.Word(obj) == species {
+				return obj, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Object is the state of an object of a loaded script.
+type Object struct {
+	// Address is the position of the object's properties in the heap.
+	Address uint16
+	Name    string
+	// Properties are the current values of each of the object's properties.
+	// The first four are the species, super class, info and name.
+	Properties []uint16
+}
+
+// objectMagic marks the start of an object.
+const objectMagic = 0x1234
+
+// Objects returns the current state of each object of the script.
+func (l LoadedScript) Objects(heap resource.Memory) ([]Object, error) {
+	var objects []Object
+	for _, block := range l.Blocks {
+		if block.Type != resource.ScriptBlockObject && block.Type != resource.ScriptBlockClass {
+			continue
+		}
+		base := l.Address + uint16(block.Offset+4)
+		if heap.Word(base) != objectMagic {
+			return nil, fmt.Errorf("script %d: no object at %04x", l.Number, base)
+		}
+		count := heap.Word(base + 6)
+		obj := Object{Address: base + 8, Properties: make([]uint16, count)}
+		for i := range obj.Properties {
+			obj.Properties[i] = heap.Word(obj.Address + uint16(2*i))
+		}
+		if len(obj.Properties) > 3 {
+			obj.Name = heap.StringAt(obj.Properties[3])
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// Locals returns the address and the current values of the local variables
+// of the script.
+func (l LoadedScript) Locals(heap resource.Memory) (uint16, []uint16) {
+	for _, block := range l.Blocks {
+		if block.Type != resource.ScriptBlockLocals {
+			continue
+		}
+		address := l.Address + uint16(block.Offset+4)
+		values := make([]uint16, len(block.Data)/2)
+		for i := range values {
+			values[i] = heap.Word(address + uint16(2*i))
+		}
+		return address, values
+	}
+	return 0, nil
+}
+
+// Globals returns the address and the current values of the global
+// variables, which are the local variables of script 0.
+func (s *SaveGame) Globals(loaded []LoadedScript) (uint16, []uint16, error) {
+	for _, l := range loaded {
+		if l.Number == 0 {
+			address, values := l.Locals(s.Heap)
+			return address, values, nil
+		}
+	}
+	return 0, nil, errors.New("script 0 is not loaded")
+}
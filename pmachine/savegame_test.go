@@ -0,0 +1,132 @@
+package pmachine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/32bitkid/sci/resource"
+)
+
+// scriptBlock packs a block of a script resource.
+func scriptBlock(t resource.ScriptBlockType, words ...uint16) []byte {
+	b := make([]byte, 4+2*len(words))
+	binary.LittleEndian.PutUint16(b, uint16(t))
+	binary.LittleEndian.PutUint16(b[2:], uint16(len(b)))
+	for i, w := range words {
+		binary.LittleEndian.PutUint16(b[4+2*i:], w)
+	}
+	return b
+}
+
+func joinBlocks(blocks ...[]byte) []byte {
+	return append(bytes.Join(blocks, nil), 0x00, 0x00)
+}
+
+const replaySelector = 0x30
+
+var (
+	// script 0, with the game object at 20, a subclass of class 5
+	mainScript = joinBlocks(
+		scriptBlock(resource.ScriptBlockExports, 1, 20),
+		scriptBlock(resource.ScriptBlockObject, objectMagic, 0, 10, 4, 0, 5, 0, 0, 0, 0),
+		scriptBlock(resource.ScriptBlockCode, 0x3a48, 0x4839, 0x1122, 0x3344),
+		scriptBlock(resource.ScriptBlockStrings, 'E'|'g'<<8, 'o', 0, 0),
+		scriptBlock(resource.ScriptBlockLocals, 0, 0),
+	)
+	// script 994, with class 5 at 12, and its replay method at 32
+	gameScript = joinBlocks(
+		scriptBlock(resource.ScriptBlockClass, objectMagic, 0, 10, 4, 5, 0xFFFF, 0x8000, 0, 1, replaySelector, 0, 32),
+		scriptBlock(resource.ScriptBlockCode, 0x7a76, 0x5455, 0x6677, 0x8899),
+	)
+)
+
+func TestSaveGame(t *testing.T) {
+	scripts := map[resource.Number]*resource.Script{}
+	for n, b := range map[resource.Number][]byte{0: mainScript, 994: gameScript} {
+		s, err := resource.NewScript(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scripts[n] = s
+	}
+
+	heap := make([]byte, 0x1000)
+	copy(heap[0x400:], mainScript)
+	copy(heap[0x800:], gameScript)
+	binary.LittleEndian.PutUint16(heap[0x400+20+6:], 0x400+48) // name -> "Ego"
+	heap[0x400+60] = 42                                        // first global
+
+	file := append([]byte("0.000.572\x00"), heap...)
+	save, err := ReadSaveGame(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if save.Version != "0.000.572" || len(save.Heap) != len(heap) {
+		t.Errorf("got version %q, and %d bytes of heap", save.Version, len(save.Heap))
+	}
+	var out bytes.Buffer
+	if _, err := save.WriteTo(&out); err != nil || !bytes.Equal(out.Bytes(), file) {
+		t.Errorf("save game does not round-trip")
+	}
+
+	loaded := save.FindScripts(scripts)
+	if len(loaded) != 2 || loaded[0].Address != 0x400 || loaded[1].Address != 0x800 {
+		t.Fatalf("got %+v", loaded)
+	}
+
+	objects, err := loaded[0].Objects(save.Heap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].Name != "Ego" || objects[0].Address != 0x400+20 {
+		t.Errorf("got objects %+v", objects)
+	}
+
+	_, globals, err := save.Globals(loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(globals) != 2 || globals[0] != 42 {
+		t.Errorf("got globals %v", globals)
+	}
+
+	var sci SCI
+	sci.SP, sci.Acc, sci.IP = 8, 1, 2
+	if err := save.Restore(&sci, loaded, replaySelector); err != nil {
+		t.Fatal(err)
+	}
+	if sci.Heap[0x400+60] != 42 {
+		t.Errorf("heap was not loaded")
+	}
+	if sci.IP != 0x800+32 || sci.SP != 0 || sci.Acc != 0 {
+		t.Errorf("got IP %04x, SP %d, Acc %d", sci.IP, sci.SP, sci.Acc)
+	}
+
+	if err := save.Restore(&sci, loaded[:1], replaySelector); err == nil {
+		t.Errorf("restored without the game class")
+	}
+}
+
+func TestSaveDirectory(t *testing.T) {
+	// two slots, as the interpreter writes them
+	file := []byte("\x03\x00In the castle\x00\x00\x00Start\x00\xff\xff")
+	slots, err := ReadSaveDirectory(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []SaveSlot{{3, "In the castle"}, {0, "Start"}}
+	if !reflect.DeepEqual(slots, expected) {
+		t.Errorf("got %+v", slots)
+	}
+
+	var out bytes.Buffer
+	if err := WriteSaveDirectory(&out, slots); err != nil || !bytes.Equal(out.Bytes(), file) {
+		t.Errorf("got %q", out.Bytes())
+	}
+
+	if _, err := ReadSaveDirectory(bytes.NewReader(file[:8])); err == nil {
+		t.Errorf("read a directory with no end")
+	}
+}
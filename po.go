@@ -0,0 +1,239 @@
+package sci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Message is a single translatable string of a game.
+type Message struct {
+	// Context identifies where the string comes from. It is unique within a
+	// game.
+	Context string
+	// Comment describes the string for the translator.
+	Comment     string
+	Source      string
+	Translation string
+	// Fuzzy marks a translation that needs to be checked. Fuzzy translations
+	// are not used.
+	Fuzzy bool
+}
+
+// Catalog is a list of the translatable strings of a game.
+type Catalog []Message
+
+// Translations returns the translation of each context. Messages that are
+// untranslated or fuzzy are left out.
+func (c Catalog) Translations() map[string]string {
+	translations := make(map[string]string)
+	for _, m := range c {
+		if m.Translation != "" && !m.Fuzzy {
+			translations[m.Context] = m.Translation
+		}
+	}
+	return translations
+}
+
+// WritePO writes the catalog as a gettext PO file.
+func (c Catalog) WritePO(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	writePOString(bw, "msgid", "")
+	writePOString(bw, "msgstr", "Content-Type: text/plain; charset=UTF-8\n")
+
+	for _, m := range c {
+		bw.WriteString("\n")
+		if m.Comment != "" {
+			for _, line := range strings.Split(m.Comment, "\n") {
+				fmt.Fprintf(bw, "#. %s\n", line)
+			}
+		}
+		fmt.Fprintf(bw, "#: %s\n", m.Context)
+		if m.Fuzzy {
+			bw.WriteString("#, fuzzy\n")
+		}
+		writePOString(bw, "msgctxt", m.Context)
+		writePOString(bw, "msgid", m.Source)
+		writePOString(bw, "msgstr", m.Translation)
+	}
+	return bw.Flush()
+}
+
+func writePOString(w *bufio.Writer, keyword, s string) {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 1 {
+		fmt.Fprintf(w, "%s %s\n", keyword, quotePO(s))
+		return
+	}
+	fmt.Fprintf(w, "%s \"\"\n", keyword)
+	for _, line := range lines {
+		fmt.Fprintf(w, "%s\n", quotePO(line))
+	}
+}
+
+func quotePO(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\%03o`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// ReadPO reads a gettext PO file. The header entry, and entries without a
+// context, are skipped.
+func ReadPO(r io.Reader) (Catalog, error) {
+	var (
+		catalog Catalog
+		scanner = bufio.NewScanner(r)
+		m       Message
+		field   *string
+		hasStr  bool
+		line    int
+	)
+
+	flush := func() {
+		if hasStr && m.Context != "" {
+			catalog = append(catalog, m)
+		}
+		m, field, hasStr = Message{}, nil, false
+	}
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "":
+			continue
+		case strings.HasPrefix(text, "#"):
+			if hasStr {
+				flush()
+			}
+			field = nil
+			switch {
+			case strings.HasPrefix(text, "#."):
+				if m.Comment != "" {
+					m.Comment += "\n"
+				}
+				m.Comment += strings.TrimSpace(text[2:])
+			case strings.HasPrefix(text, "#,"):
+				for _, flag := range strings.Split(text[2:], ",") {
+					if strings.TrimSpace(flag) == "fuzzy" {
+						m.Fuzzy = true
+					}
+				}
+			}
+			continue
+		case strings.HasPrefix(text, `"`):
+			if field == nil {
+				return nil, fmt.Errorf("line %d: unexpected string", line)
+			}
+			s, err := unquotePO(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			*field += s
+			continue
+		}
+
+		parts := strings.SplitN(text, " ", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: invalid entry", line)
+		}
+		switch parts[0] {
+		case "msgctxt", "msgid":
+			if hasStr {
+				flush()
+			}
+			if parts[0] == "msgctxt" {
+				field = &m.Context
+			} else {
+				field = &m.Source
+			}
+		case "msgstr", "msgstr[0]":
+			field, hasStr = &m.Translation, true
+		default:
+			// plural forms are not used by games
+			field = new(string)
+		}
+
+		s, err := unquotePO(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		*field += s
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	return catalog, nil
+}
+
+func unquotePO(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("invalid string %s", s)
+	}
+	s = s[1 : len(s)-1]
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return "", fmt.Errorf("invalid escape at end of string")
+		}
+		switch c := s[i]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\':
+			b.WriteByte(c)
+		default:
+			end := i
+			for end < len(s) && end < i+3 && s[end] >= '0' && s[end] <= '7' {
+				end++
+			}
+			if end == i {
+				return "", fmt.Errorf("invalid escape \\%c", c)
+			}
+			v, err := strconv.ParseUint(s[i:end], 8, 8)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape \\%s", s[i:end])
+			}
+			b.WriteByte(uint8(v))
+			i = end - 1
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,112 @@
+package sci
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"testing"
+
+	"github.com/32bitkid/sci/resource"
+	"github.com/32bitkid/sci/screen"
+)
+
+// BenchmarkRenderGame draws every picture of the SCI0 game in the directory
+// named by SCI_GAME.
+func BenchmarkRenderGame(b *testing.B) {
+	dir := os.Getenv("SCI_GAME")
+	if dir == "" {
+		b.Skip("SCI_GAME is not set")
+	}
+	root := NewSCI0Root(dir)
+	if err := root.LoadMapping(); err != nil {
+		b.Fatal(err)
+	}
+
+	var pics [][]byte
+	for _, m := range root.Mapping {
+		if m.Type() != resource.TypePic {
+			continue
+		}
+		res, err := m.Resource()
+		if err != nil {
+			b.Fatal(err)
+		}
+		pics = append(pics, res.Bytes())
+	}
+	if len(pics) == 0 {
+		b.Skip("no pictures in", dir)
+	}
+
+	scalers := []struct {
+		name   string
+		scaler screen.Scaler
+	}{
+		{"1x1", screen.Scaler1x1{}},
+		{"5x6", screen.Scaler5x6{}},
+	}
+	for _, s := range scalers {
+		b.Run(s.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, pic := range pics {
+					if _, err := resource.NewPic(pic, resource.PicOptions{Scaler: s.scaler}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestEncodeGamePics re-encodes every picture of the SCI0 game in the
+// directory named by SCI_GAME, and checks that each one draws the same layers
+// as the original.
+func TestEncodeGamePics(t *testing.T) {
+	dir := os.Getenv("SCI_GAME")
+	if dir == "" {
+		t.Skip("SCI_GAME is not set")
+	}
+	root := NewSCI0Root(dir)
+	if err := root.LoadMapping(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range root.Mapping {
+		if m.Type() != resource.TypePic {
+			continue
+		}
+		res, err := m.Resource()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cmds, err := resource.DecodePicCommands(res.Bytes())
+		if err != nil {
+			t.Fatalf("pic %d: %v", m.Number(), err)
+		}
+		encoded, err := resource.EncodePicCommands(cmds)
+		if err != nil {
+			t.Fatalf("pic %d: %v", m.Number(), err)
+		}
+
+		expected, err := resource.NewPic(res.Bytes())
+		if err != nil {
+			t.Fatalf("pic %d: %v", m.Number(), err)
+		}
+		actual, err := resource.NewPic(encoded)
+		if err != nil {
+			t.Fatalf("pic %d: %v", m.Number(), err)
+		}
+		layers := map[string][2]screen.Buffer{
+			"visual":   {expected.Visual(), actual.Visual()},
+			"priority": {expected.Priority(), actual.Priority()},
+			"control":  {expected.Control(), actual.Control()},
+		}
+		for name, l := range layers {
+			e, a := l[0].Image().(*image.Paletted), l[1].Image().(*image.Paletted)
+			if !bytes.Equal(e.Pix, a.Pix) {
+				t.Errorf("pic %d: %s layer differs", m.Number(), name)
+			}
+		}
+	}
+}
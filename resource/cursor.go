@@ -3,6 +3,9 @@ package resource
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
 )
 
 func NewCursor(b []byte) (cursor Cursor, err error) {
@@ -16,32 +19,171 @@ type HotSpot struct {
 	Y int16
 }
 
+// CenteredHotSpot is a hot spot with the flag that centers the hot spot of
+// an SCI0 cursor.
+var CenteredHotSpot = HotSpot{X: 0, Y: 0x100}
+
+// Point returns the pixel of a 16x16 SCI0 cursor that is at the position of
+// the mouse. SCI0 only looks at the lowest bit of the fourth byte of a
+// cursor. When it is set the hot spot is the center of the cursor, and
+// otherwise it is the top-left pixel, as in ScummVM's
+// GfxCursor::kernelSetShape.
+func (h HotSpot) Point() image.Point {
+	if uint16(h.Y)>>8&1 != 0 {
+		return image.Point{X: 8, Y: 8}
+	}
+	return image.Point{}
+}
+
+// PointSCI01 returns the pixel of a 16x16 SCI01 cursor that is at the
+// position of the mouse. SCI01 cursors give their hot spot as coordinates,
+// and hot spots outside of the cursor are moved to its nearest edge.
+func (h HotSpot) PointSCI01() image.Point {
+	clamp := func(v int16) int {
+		switch {
+		case v < 0:
+			return 0
+		case v > 15:
+			return 15
+		}
+		return int(v)
+	}
+	return image.Point{X: clamp(h.X), Y: clamp(h.Y)}
+}
+
+// Cursor is a 16x16 mouse cursor. Each row of the masks is a word, with the
+// left-most pixel in the highest bit.
 type Cursor struct {
 	HotSpot
 	Transparency [16]uint16
 	Color        [16]uint16
 }
 
-func (c Cursor) String() string {
-	str := ""
-	for i := 0; i < 256; i++ {
-		x := uint16(i & 0xF)
-		y := uint16(i >> 4)
+// CursorPixel is one of the four kinds of pixel that a cursor can have.
+type CursorPixel uint8
 
-		tr := (c.Transparency[y]>>x)&1 == 1
-		clr := (c.Color[y]>>x)&1 == 1
-		switch {
-		case tr:
-			str += " "
-		case clr:
-			str += "\u2588"
-		default:
-			str += "\u2591"
+const (
+	CursorBlack CursorPixel = iota
+	CursorWhite
+	CursorTransparent
+	CursorInverted
+)
+
+// CursorInvertedColor is the color that inverted pixels are given in an
+// image, as they cannot be represented otherwise.
+var CursorInvertedColor = color.NRGBA{R: 0xAA, G: 0xAA, B: 0xAA, A: 0xFF}
+
+// At returns the kind of pixel at x, y.
+func (c Cursor) At(x, y int) CursorPixel {
+	if x < 0 || y < 0 || x >= 16 || y >= 16 {
+		return CursorTransparent
+	}
+	bit := uint16(0x8000) >> uint(x)
+	var p CursorPixel
+	if c.Transparency[y]&bit != 0 {
+		p |= CursorTransparent
+	}
+	if c.Color[y]&bit != 0 {
+		p |= CursorWhite
+	}
+	return p
+}
+
+// Set changes the kind of pixel at x, y.
+func (c *Cursor) Set(x, y int, p CursorPixel) {
+	if x < 0 || y < 0 || x >= 16 || y >= 16 {
+		return
+	}
+	bit := uint16(0x8000) >> uint(x)
+	c.Transparency[y] &^= bit
+	c.Color[y] &^= bit
+	if p&CursorTransparent != 0 {
+		c.Transparency[y] |= bit
+	}
+	if p&CursorWhite != 0 {
+		c.Color[y] |= bit
+	}
+}
+
+// Image returns the cursor as an image. Inverted pixels are drawn with the
+// CursorInvertedColor.
+func (c Cursor) Image() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			switch c.At(x, y) {
+			case CursorBlack:
+				img.SetNRGBA(x, y, color.NRGBA{A: 0xFF})
+			case CursorWhite:
+				img.SetNRGBA(x, y, color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF})
+			case CursorInverted:
+				img.SetNRGBA(x, y, CursorInvertedColor)
+			}
+		}
+	}
+	return img
+}
+
+// Encode packs the cursor into a cursor resource.
+func (c Cursor) Encode() []byte {
+	var w bytes.Buffer
+	binary.Write(&w, binary.LittleEndian, c)
+	return w.Bytes()
+}
+
+// NewCursorFromImage converts a 16x16 image into a cursor. Pixels that are
+// less than half opaque are transparent, pixels of the CursorInvertedColor
+// are inverted, and the rest are black or white, whichever is closer.
+func NewCursorFromImage(img image.Image, hotSpot HotSpot) (Cursor, error) {
+	bounds := img.Bounds()
+	if bounds.Dx() != 16 || bounds.Dy() != 16 {
+		return Cursor{}, fmt.Errorf("cursor image is %dx%d, expected 16x16", bounds.Dx(), bounds.Dy())
+	}
+
+	cursor := Cursor{HotSpot: hotSpot}
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			switch {
+			case c.A < 0x80:
+				cursor.Set(x, y, CursorTransparent)
+			case c == CursorInvertedColor:
+				cursor.Set(x, y, CursorInverted)
+			case color.GrayModel.Convert(c).(color.Gray).Y >= 0x80:
+				cursor.Set(x, y, CursorWhite)
+			default:
+				cursor.Set(x, y, CursorBlack)
+			}
 		}
+	}
+	return cursor, nil
+}
 
-		if x == 15 {
-			str += "\n"
+// EncodeCursor converts a 16x16 image into a cursor resource.
+func EncodeCursor(img image.Image, hotSpot HotSpot) ([]byte, error) {
+	cursor, err := NewCursorFromImage(img, hotSpot)
+	if err != nil {
+		return nil, err
+	}
+	return cursor.Encode(), nil
+}
+
+func (c Cursor) String() string {
+	str := ""
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			switch c.At(x, y) {
+			case CursorTransparent:
+				str += " "
+			case CursorWhite:
+				str += "\u2588"
+			case CursorInverted:
+				str += "\u2592"
+			default:
+				str += "\u2591"
+			}
 		}
+		str += "\n"
 	}
 	return str
 }
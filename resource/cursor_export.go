@@ -0,0 +1,84 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// WriteCUR writes the cursor as a Windows .cur file. The masks of a cursor
+// resource work the same way as those of a .cur, so inverted pixels are kept.
+// The hot spot is the one given by HotSpot.Point.
+func (c Cursor) WriteCUR(w io.Writer) error {
+	const (
+		headerSize = 6 + 16
+		bitmapSize = 40 + 2*4 + 2*16*4
+	)
+
+	var b bytes.Buffer
+	le := func(v interface{}) { binary.Write(&b, binary.LittleEndian, v) }
+
+	// ICONDIR and its one ICONDIRENTRY
+	le([3]uint16{0, 2, 1})
+	le([4]uint8{16, 16, 2, 0})
+	hot := c.HotSpot.Point()
+	le([2]uint16{uint16(hot.X), uint16(hot.Y)})
+	le([2]uint32{bitmapSize, headerSize})
+
+	// BITMAPINFOHEADER, with the height of both masks
+	le(struct {
+		Size          uint32
+		Width, Height int32
+		Planes, Bits  uint16
+		Compression   uint32
+		ImageSize     uint32
+		XPPM, YPPM    int32
+		Used          uint32
+		Important     uint32
+	}{Size: 40, Width: 16, Height: 32, Planes: 1, Bits: 1, ImageSize: 2 * 16 * 4, Used: 2})
+	le([2]uint32{0x000000, 0xFFFFFF})
+
+	// the XOR then AND masks, bottom row first, padded to 32 bits
+	for _, mask := range [][16]uint16{c.Color, c.Transparency} {
+		for y := 15; y >= 0; y-- {
+			b.Write([]byte{uint8(mask[y] >> 8), uint8(mask[y]), 0, 0})
+		}
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// WriteXcursor writes the cursor in the X11 Xcursor format. Inverted pixels
+// are drawn with the CursorInvertedColor, and the hot spot is the one given
+// by HotSpot.Point.
+func (c Cursor) WriteXcursor(w io.Writer) error {
+	const (
+		fileHeaderSize  = 16
+		tocSize         = 12
+		imageHeaderSize = 36
+		imageType       = 0xFFFD0002
+		nominalSize     = 16
+	)
+
+	var b bytes.Buffer
+	le := func(v interface{}) { binary.Write(&b, binary.LittleEndian, v) }
+
+	b.WriteString("Xcur")
+	le([3]uint32{fileHeaderSize, 0x10000, 1})
+	le([3]uint32{imageType, nominalSize, fileHeaderSize + tocSize})
+	hot := c.HotSpot.Point()
+	le([9]uint32{imageHeaderSize, imageType, nominalSize, 1, 16, 16, uint32(hot.X), uint32(hot.Y), 0})
+
+	img := c.Image()
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			// premultiplied ARGB
+			r, g, bl, a := img.At(x, y).RGBA()
+			le(a>>8<<24 | r>>8<<16 | g>>8<<8 | bl>>8)
+		}
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
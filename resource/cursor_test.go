@@ -0,0 +1,100 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"testing"
+)
+
+func TestCursorImageRoundTrip(t *testing.T) {
+	var cursor Cursor
+	cursor.HotSpot = CenteredHotSpot
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			cursor.Set(x, y, CursorPixel((x+y)%4))
+		}
+	}
+	if cursor.Transparency[0] != 0x3333 || cursor.Color[0] != 0x5555 {
+		t.Errorf("pixels should be stored with the left-most pixel in the highest bit")
+	}
+
+	b, err := EncodeCursor(cursor.Image(), cursor.HotSpot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := NewCursor(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != cursor {
+		t.Errorf("cursor changed after converting to an image:\n%v\n%v", decoded, cursor)
+	}
+
+	var cur bytes.Buffer
+	if err := cursor.WriteCUR(&cur); err != nil {
+		t.Fatal(err)
+	}
+	if cur.Len() != 22+40+8+2*64 {
+		t.Errorf("got .cur of %d bytes", cur.Len())
+	}
+	if x, y := binary.LittleEndian.Uint16(cur.Bytes()[10:]), binary.LittleEndian.Uint16(cur.Bytes()[12:]); x != 8 || y != 8 {
+		t.Errorf("got hot spot %d,%d, expected 8,8", x, y)
+	}
+}
+
+func TestCursorHotSpot(t *testing.T) {
+	tests := []struct {
+		name     string
+		hotSpot  HotSpot
+		expected [2]uint16
+	}{
+		{"top-left", HotSpot{}, [2]uint16{0, 0}},
+		{"centered", CenteredHotSpot, [2]uint16{8, 8}},
+		{"flag and other bits", HotSpot{Y: 0x0300}, [2]uint16{8, 8}},
+		{"other bits", HotSpot{Y: 0x0200}, [2]uint16{0, 0}},
+		{"low byte", HotSpot{Y: 0x0005}, [2]uint16{0, 0}},
+		{"coordinates", HotSpot{X: 3, Y: 15}, [2]uint16{0, 0}},
+	}
+	for _, tt := range tests {
+		// the hot spot, as it is stored in a cursor resource
+		b := append([]byte{uint8(tt.hotSpot.X), uint8(tt.hotSpot.X >> 8), uint8(tt.hotSpot.Y), uint8(tt.hotSpot.Y >> 8)}, make([]byte, 64)...)
+		cursor, err := NewCursor(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var cur, xcur bytes.Buffer
+		if err := cursor.WriteCUR(&cur); err != nil {
+			t.Fatal(err)
+		}
+		if err := cursor.WriteXcursor(&xcur); err != nil {
+			t.Fatal(err)
+		}
+
+		le := binary.LittleEndian
+		if x, y := le.Uint16(cur.Bytes()[10:]), le.Uint16(cur.Bytes()[12:]); [2]uint16{x, y} != tt.expected {
+			t.Errorf("%s: got .cur hot spot %d,%d, expected %v", tt.name, x, y, tt.expected)
+		}
+		if x, y := le.Uint32(xcur.Bytes()[52:]), le.Uint32(xcur.Bytes()[56:]); [2]uint16{uint16(x), uint16(y)} != tt.expected {
+			t.Errorf("%s: got Xcursor hot spot %d,%d, expected %v", tt.name, x, y, tt.expected)
+		}
+	}
+}
+
+func TestCursorHotSpotSCI01(t *testing.T) {
+	tests := []struct {
+		hotSpot  HotSpot
+		expected image.Point
+	}{
+		{HotSpot{}, image.Point{}},
+		{HotSpot{X: 3, Y: 15}, image.Point{X: 3, Y: 15}},
+		{HotSpot{X: 40, Y: -3}, image.Point{X: 15}},
+		{CenteredHotSpot, image.Point{Y: 15}},
+	}
+	for _, tt := range tests {
+		if p := tt.hotSpot.PointSCI01(); p != tt.expected {
+			t.Errorf("%v: got %v, expected %v", tt.hotSpot, p, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package resource
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// FontAtlas is a font drawn as a grid of characters in a single image, so
+// that it can be edited in an image editor. The metrics describe where each
+// character is in the image, and can be stored alongside it as JSON.
+type FontAtlas struct {
+	Image      image.Image  `json:"-"`
+	LineHeight int          `json:"lineHeight"`
+	Glyphs     []AtlasGlyph `json:"glyphs"`
+}
+
+// AtlasGlyph is the area of a FontAtlas that holds a single character.
+type AtlasGlyph struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Atlas draws the font in a grid that is columns characters wide. Set pixels
+// are white, and everything else is transparent.
+func (f *Font) Atlas(columns int) *FontAtlas {
+	if columns <= 0 {
+		columns = 16
+	}
+
+	cellW, cellH := 1, int(f.LineHeight)
+	for _, ch := range f.Characters {
+		if int(ch.Width) > cellW {
+			cellW = int(ch.Width)
+		}
+		if int(ch.Height) > cellH {
+			cellH = int(ch.Height)
+		}
+	}
+
+	rows := (len(f.Characters) + columns - 1) / columns
+	img := image.NewPaletted(
+		image.Rect(0, 0, columns*cellW, rows*cellH),
+		color.Palette{color.Transparent, color.White},
+	)
+
+	atlas := &FontAtlas{
+		Image:      img,
+		LineHeight: int(f.LineHeight),
+		Glyphs:     make([]AtlasGlyph, len(f.Characters)),
+	}
+	for i, ch := range f.Characters {
+		g := AtlasGlyph{
+			X:      (i % columns) * cellW,
+			Y:      (i / columns) * cellH,
+			Width:  int(ch.Width),
+			Height: int(ch.Height),
+		}
+		for y := 0; y < g.Height; y++ {
+			for x := 0; x < g.Width; x++ {
+				if ch.At(x, y) {
+					img.SetColorIndex(g.X+x, g.Y+y, 1)
+				}
+			}
+		}
+		atlas.Glyphs[i] = g
+	}
+	return atlas
+}
+
+// Font reads the characters back out of the atlas. Any pixel that is at least
+// half opaque is set.
+func (a *FontAtlas) Font() (*Font, error) {
+	if a.Image == nil {
+		return nil, errors.New("atlas has no image")
+	}
+	if a.LineHeight < 0 || a.LineHeight > 0xFFFF || len(a.Glyphs) > 0xFFFF {
+		return nil, errors.New("invalid atlas metrics")
+	}
+
+	font := &Font{
+		Count:      uint16(len(a.Glyphs)),
+		LineHeight: uint16(a.LineHeight),
+		Characters: make([]Character, len(a.Glyphs)),
+	}
+
+	bounds := a.Image.Bounds()
+	for i, g := range a.Glyphs {
+		if g.Width < 0 || g.Height < 0 || g.Width > 0xFF || g.Height > 0xFF {
+			return nil, fmt.Errorf("glyph %d has an invalid size", i)
+		}
+		ch := NewCharacter(uint8(g.Width), uint8(g.Height))
+		for y := 0; y < g.Height; y++ {
+			for x := 0; x < g.Width; x++ {
+				p := bounds.Min.Add(image.Point{X: g.X + x, Y: g.Y + y})
+				if !p.In(bounds) {
+					return nil, fmt.Errorf("glyph %d is outside of the image", i)
+				}
+				if _, _, _, alpha := a.Image.At(p.X, p.Y).RGBA(); alpha >= 0x8000 {
+					ch.Set(x, y, true)
+				}
+			}
+		}
+		font.Characters[i] = ch
+	}
+	return font, nil
+}
+
+// Encode writes the image of the atlas as a PNG, and its metrics as JSON.
+func (a *FontAtlas) Encode(img io.Writer, metrics io.Writer) error {
+	if err := png.Encode(img, a.Image); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(metrics)
+	enc.SetIndent("", "  ")
+	return enc.Encode(a)
+}
+
+// DecodeFontAtlas reads an atlas that was written by FontAtlas.Encode.
+func DecodeFontAtlas(img io.Reader, metrics io.Reader) (*FontAtlas, error) {
+	var atlas FontAtlas
+	if err := json.NewDecoder(metrics).Decode(&atlas); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if atlas.Image, err = png.Decode(img); err != nil {
+		return nil, err
+	}
+	return &atlas, nil
+}
@@ -0,0 +1,214 @@
+package resource
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteBDF writes the font in the Glyph Bitmap Distribution Format. Each
+// character is encoded as its index. Fonts have no baseline, so the baseline
+// is placed at the bottom of the line.
+func (f *Font) WriteBDF(w io.Writer, name string) error {
+	lh := int(f.LineHeight)
+
+	maxWidth, maxHeight := 0, lh
+	for _, ch := range f.Characters {
+		if int(ch.Width) > maxWidth {
+			maxWidth = int(ch.Width)
+		}
+		if int(ch.Height) > maxHeight {
+			maxHeight = int(ch.Height)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "STARTFONT 2.1\n")
+	fmt.Fprintf(bw, "FONT %s\n", name)
+	fmt.Fprintf(bw, "SIZE %d 72 72\n", lh)
+	fmt.Fprintf(bw, "FONTBOUNDINGBOX %d %d 0 %d\n", maxWidth, maxHeight, lh-maxHeight)
+	fmt.Fprintf(bw, "STARTPROPERTIES 2\n")
+	fmt.Fprintf(bw, "FONT_ASCENT %d\n", lh)
+	fmt.Fprintf(bw, "FONT_DESCENT 0\n")
+	fmt.Fprintf(bw, "ENDPROPERTIES\n")
+	fmt.Fprintf(bw, "CHARS %d\n", len(f.Characters))
+
+	for i, ch := range f.Characters {
+		swidth := 0
+		if lh > 0 {
+			swidth = int(ch.Width) * 1000 / lh
+		}
+		fmt.Fprintf(bw, "STARTCHAR char%d\n", i)
+		fmt.Fprintf(bw, "ENCODING %d\n", i)
+		fmt.Fprintf(bw, "SWIDTH %d 0\n", swidth)
+		fmt.Fprintf(bw, "DWIDTH %d 0\n", ch.Width)
+		fmt.Fprintf(bw, "BBX %d %d 0 %d\n", ch.Width, ch.Height, lh-int(ch.Height))
+		fmt.Fprintf(bw, "BITMAP\n")
+		bpr := (int(ch.Width) + 7) >> 3
+		for y := 0; y < int(ch.Height); y++ {
+			fmt.Fprintf(bw, "%X\n", ch.Bitmaps[y*bpr:(y+1)*bpr])
+		}
+		fmt.Fprintf(bw, "ENDCHAR\n")
+	}
+	fmt.Fprintf(bw, "ENDFONT\n")
+
+	return bw.Flush()
+}
+
+// bdfGlyph is a single character of a BDF font.
+type bdfGlyph struct {
+	encoding   int
+	advance    int
+	w, h, x, y int
+	rows       [][]byte
+}
+
+// ReadBDF reads a font in the Glyph Bitmap Distribution Format. Glyphs with an
+// encoding of 0 to 255 become the character at that index. Each character is
+// as wide as its advance, and as tall as needed to reach the bottom of its
+// glyph from the top of the line. Pixels outside of that area are dropped.
+func ReadBDF(r io.Reader) (*Font, error) {
+	var (
+		scanner        = bufio.NewScanner(r)
+		ascent         = -1
+		descent        = -1
+		bbxH, bbxY     int
+		glyphs         []bdfGlyph
+		glyph          *bdfGlyph
+		bitmap         bool
+		line           int
+		defaultAdvance int
+	)
+
+	ints := func(fields []string, n int) ([]int, error) {
+		if len(fields) < n+1 {
+			return nil, fmt.Errorf("line %d: expected %d values for %s", line, n, fields[0])
+		}
+		values := make([]int, n)
+		for i := range values {
+			v, err := strconv.Atoi(fields[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			values[i] = v
+		}
+		return values, nil
+	}
+
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if bitmap && fields[0] != "ENDCHAR" {
+			row, err := hex.DecodeString(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			glyph.rows = append(glyph.rows, row)
+			continue
+		}
+
+		var (
+			v   []int
+			err error
+		)
+		switch fields[0] {
+		case "FONTBOUNDINGBOX":
+			if v, err = ints(fields, 4); err == nil {
+				bbxH, bbxY = v[1], v[3]
+			}
+		case "FONT_ASCENT":
+			if v, err = ints(fields, 1); err == nil {
+				ascent = v[0]
+			}
+		case "FONT_DESCENT":
+			if v, err = ints(fields, 1); err == nil {
+				descent = v[0]
+			}
+		case "DWIDTH":
+			if v, err = ints(fields, 2); err == nil {
+				if glyph != nil {
+					glyph.advance = v[0]
+				} else {
+					defaultAdvance = v[0]
+				}
+			}
+		case "STARTCHAR":
+			glyph = &bdfGlyph{encoding: -1, advance: defaultAdvance}
+		case "ENCODING":
+			if glyph != nil {
+				if v, err = ints(fields, 1); err == nil {
+					glyph.encoding = v[0]
+				}
+			}
+		case "BBX":
+			if glyph != nil {
+				if v, err = ints(fields, 4); err == nil {
+					glyph.w, glyph.h, glyph.x, glyph.y = v[0], v[1], v[2], v[3]
+				}
+			}
+		case "BITMAP":
+			bitmap = glyph != nil
+		case "ENDCHAR":
+			if glyph != nil {
+				glyphs = append(glyphs, *glyph)
+			}
+			glyph, bitmap = nil, false
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if ascent < 0 {
+		ascent = bbxH + bbxY
+	}
+	if descent < 0 {
+		descent = -bbxY
+	}
+	if ascent+descent <= 0 || ascent+descent > 0xFFFF {
+		return nil, errors.New("invalid font height")
+	}
+
+	font := &Font{LineHeight: uint16(ascent + descent)}
+	for _, g := range glyphs {
+		if g.encoding < 0 || g.encoding > 0xFF {
+			continue
+		}
+		if g.encoding >= len(font.Characters) {
+			font.Characters = append(font.Characters, make([]Character, g.encoding+1-len(font.Characters))...)
+		}
+
+		top := ascent - (g.y + g.h)
+		width, height := g.advance, top+g.h
+		if width < 0 || height < 0 {
+			width, height = 0, 0
+		}
+		if width > 0xFF || height > 0xFF {
+			return nil, fmt.Errorf("character %d is too large", g.encoding)
+		}
+
+		ch := NewCharacter(uint8(width), uint8(height))
+		for y, row := range g.rows {
+			for x := 0; x < g.w && x>>3 < len(row); x++ {
+				if (row[x>>3]>>(7-uint(x&7)))&1 == 1 {
+					ch.Set(g.x+x, top+y, true)
+				}
+			}
+		}
+		font.Characters[g.encoding] = ch
+	}
+	font.Count = uint16(len(font.Characters))
+
+	return font, nil
+}
@@ -0,0 +1,210 @@
+package resource
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// At reports whether the pixel at x, y of the character is set.
+func (c Character) At(x, y int) bool {
+	if x < 0 || y < 0 || x >= int(c.Width) || y >= int(c.Height) {
+		return false
+	}
+	bpr := (int(c.Width) + 7) >> 3
+	return (c.Bitmaps[y*bpr+x>>3]>>(7-uint(x&7)))&1 == 1
+}
+
+// Mask returns the character as an alpha mask.
+func (c Character) Mask() *image.Alpha {
+	mask := image.NewAlpha(image.Rect(0, 0, int(c.Width), int(c.Height)))
+	for y := 0; y < int(c.Height); y++ {
+		for x := 0; x < int(c.Width); x++ {
+			if c.At(x, y) {
+				mask.Pix[y*mask.Stride+x] = 0xFF
+			}
+		}
+	}
+	return mask
+}
+
+// character returns the character for the byte b. Bytes without a
+// character are drawn as nothing.
+func (f *Font) character(b byte) Character {
+	if int(b) >= len(f.Characters) {
+		return Character{}
+	}
+	return f.Characters[b]
+}
+
+func (f *Font) width(s string) int {
+	w := 0
+	for i := 0; i < len(s); i++ {
+		w += int(f.character(s[i]).Width)
+	}
+	return w
+}
+
+// Measure returns the size of the area that s covers when it is drawn. Each
+// byte of s is a character, and newlines start a new line.
+func (f *Font) Measure(s string) image.Point {
+	lines := f.Wrap(s, 0)
+	size := image.Point{Y: len(lines) * int(f.LineHeight)}
+	for _, line := range lines {
+		if w := f.width(line); w > size.X {
+			size.X = w
+		}
+	}
+	return size
+}
+
+// Wrap splits s into lines that are no wider than maxWidth pixels, in the same
+// way as the interpreter lays out the text of a message box. Lines are broken
+// at newlines, and at the last space that fits. The space is dropped. A word
+// that does not fit on a line by itself is broken at the last character that
+// fits. If maxWidth is 0 or less, lines are only broken at newlines.
+func (f *Font) Wrap(s string, maxWidth int) []string {
+	var lines []string
+	for {
+		end, next := f.longest(s, maxWidth)
+		lines = append(lines, s[:end])
+		if next >= len(s) {
+			return lines
+		}
+		s = s[next:]
+	}
+}
+
+// longest returns the end of the first line of s, and the start of the line
+// after it.
+func (f *Font) longest(s string, maxWidth int) (int, int) {
+	width, lastSpace := 0, -1
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\n':
+			return i, i + 1
+		case '\r':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				return i, i + 2
+			}
+			return i, i + 1
+		case ' ':
+			lastSpace = i
+		}
+
+		width += int(f.character(s[i]).Width)
+		if maxWidth <= 0 || width <= maxWidth {
+			continue
+		}
+
+		switch {
+		case lastSpace >= 0:
+			return lastSpace, lastSpace + 1
+		case i == 0:
+			return 1, 1
+		default:
+			return i, i
+		}
+	}
+	return len(s), len(s)
+}
+
+// DrawString draws s onto dst with its top-left corner at pt. Each byte of s
+// is a character, and newlines start a new line, LineHeight pixels below.
+// Lines are not wrapped, see DrawStringBox.
+func (f *Font) DrawString(dst draw.Image, pt image.Point, s string, c color.Color) {
+	f.drawLines(dst, pt, f.Wrap(s, 0), dst.Bounds(), c)
+}
+
+// DrawStringBox draws s onto dst inside of r, with the lines wrapped to the
+// width of r by Wrap. Pixels that fall outside of r, such as those of lines
+// below it, are not drawn.
+func (f *Font) DrawStringBox(dst draw.Image, r image.Rectangle, s string, c color.Color) {
+	f.drawLines(dst, r.Min, f.Wrap(s, r.Dx()), r.Intersect(dst.Bounds()), c)
+}
+
+// drawLines draws lines of text, starting with the top-left corner of the
+// first line at pt. Only the pixels inside of clip are drawn.
+func (f *Font) drawLines(dst draw.Image, pt image.Point, lines []string, clip image.Rectangle, c color.Color) {
+	x, y := pt.X, pt.Y
+	for _, line := range lines {
+		for i := 0; i < len(line); i++ {
+			ch := f.character(line[i])
+			for cy := 0; cy < int(ch.Height); cy++ {
+				for cx := 0; cx < int(ch.Width); cx++ {
+					p := image.Point{X: x + cx, Y: y + cy}
+					if ch.At(cx, cy) && p.In(clip) {
+						dst.Set(p.X, p.Y, c)
+					}
+				}
+			}
+			x += int(ch.Width)
+		}
+		x, y = pt.X, y+int(f.LineHeight)
+	}
+}
+
+// Face adapts the font to a font.Face. Runes are mapped directly to the
+// character with the same index. Fonts have no baseline, so the ascent is
+// the whole LineHeight, and glyphs hang down from the top of the line.
+func (f *Font) Face() font.Face {
+	return &fontFace{
+		Font:  f,
+		masks: make([]*image.Alpha, len(f.Characters)),
+	}
+}
+
+type fontFace struct {
+	*Font
+	masks []*image.Alpha
+}
+
+func (face *fontFace) lookup(r rune) (Character, bool) {
+	if r < 0 || int(r) >= len(face.Characters) {
+		return Character{}, false
+	}
+	return face.Characters[r], true
+}
+
+func (face *fontFace) Close() error { return nil }
+
+func (face *fontFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	ch, ok := face.lookup(r)
+	if !ok {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	if face.masks[r] == nil {
+		face.masks[r] = ch.Mask()
+	}
+
+	top := image.Point{X: dot.X.Round(), Y: dot.Y.Round() - int(face.LineHeight)}
+	dr := image.Rect(0, 0, int(ch.Width), int(ch.Height)).Add(top)
+	return dr, face.masks[r], image.Point{}, fixed.I(int(ch.Width)), true
+}
+
+func (face *fontFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	ch, ok := face.lookup(r)
+	if !ok {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	top := -int(face.LineHeight)
+	bounds := fixed.R(0, top, int(ch.Width), top+int(ch.Height))
+	return bounds, fixed.I(int(ch.Width)), true
+}
+
+func (face *fontFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	ch, ok := face.lookup(r)
+	return fixed.I(int(ch.Width)), ok
+}
+
+func (face *fontFace) Kern(_, _ rune) fixed.Int26_6 { return 0 }
+
+func (face *fontFace) Metrics() font.Metrics {
+	return font.Metrics{
+		Height: fixed.I(int(face.LineHeight)),
+		Ascent: fixed.I(int(face.LineHeight)),
+	}
+}
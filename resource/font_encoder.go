@@ -0,0 +1,61 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Encode packs the font into a font resource. Every entry of Characters is
+// written, and Count is ignored.
+func (f *Font) Encode() ([]byte, error) {
+	if len(f.Characters) > 0xFFFF {
+		return nil, errors.New("font has too many characters")
+	}
+
+	var w bytes.Buffer
+	binary.Write(&w, binary.LittleEndian, [3]uint16{0, uint16(len(f.Characters)), f.LineHeight})
+
+	pointers := w.Len()
+	w.Write(make([]byte, 2*len(f.Characters)))
+
+	for i, ch := range f.Characters {
+		bitmapLength := ((int(ch.Width) + 7) >> 3) * int(ch.Height)
+		if len(ch.Bitmaps) != bitmapLength {
+			return nil, fmt.Errorf("character %d has %d bytes, expected %d", i, len(ch.Bitmaps), bitmapLength)
+		}
+		if w.Len() > 0xFFFF {
+			return nil, errors.New("font is too large")
+		}
+		binary.LittleEndian.PutUint16(w.Bytes()[pointers+2*i:], uint16(w.Len()))
+		w.WriteByte(ch.Width)
+		w.WriteByte(ch.Height)
+		w.Write(ch.Bitmaps)
+	}
+
+	return w.Bytes(), nil
+}
+
+// NewCharacter creates an empty character of the given size.
+func NewCharacter(width, height uint8) Character {
+	return Character{
+		Width:   width,
+		Height:  height,
+		Bitmaps: make([]byte, ((int(width)+7)>>3)*int(height)),
+	}
+}
+
+// Set sets or clears the pixel at x, y of the character.
+func (c Character) Set(x, y int, on bool) {
+	if x < 0 || y < 0 || x >= int(c.Width) || y >= int(c.Height) {
+		return
+	}
+	bpr := (int(c.Width) + 7) >> 3
+	bit := uint8(0x80) >> uint(x&7)
+	if on {
+		c.Bitmaps[y*bpr+x>>3] |= bit
+	} else {
+		c.Bitmaps[y*bpr+x>>3] &^= bit
+	}
+}
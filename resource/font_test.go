@@ -0,0 +1,146 @@
+package resource
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// testFont has a 3x4 box for every character, and a 2 pixel wide space.
+func testFont() *Font {
+	f := &Font{Count: 128, LineHeight: 5, Characters: make([]Character, 128)}
+	for i := range f.Characters {
+		f.Characters[i] = Character{Width: 3, Height: 4, Bitmaps: []byte{0xE0, 0xA0, 0xA0, 0xE0}}
+	}
+	f.Characters[' '] = Character{Width: 2, Height: 4, Bitmaps: make([]byte, 4)}
+	return f
+}
+
+func TestFontWrap(t *testing.T) {
+	f := testFont()
+	tests := []struct {
+		s        string
+		maxWidth int
+		expected []string
+	}{
+		{"abc def", 0, []string{"abc def"}},
+		{"abc def", 12, []string{"abc", "def"}},
+		{"ab cd ef", 17, []string{"ab cd", "ef"}},
+		{"abcdef", 10, []string{"abc", "def"}},
+		{"ab\r\ncd\nef", 100, []string{"ab", "cd", "ef"}},
+	}
+	for _, tt := range tests {
+		if actual := f.Wrap(tt.s, tt.maxWidth); !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("Wrap(%q, %d) = %q, expected %q", tt.s, tt.maxWidth, actual, tt.expected)
+		}
+	}
+
+	if size := f.Measure("ab\nc d e"); size != (image.Point{X: 13, Y: 10}) {
+		t.Errorf("got size %v, expected 13x10", size)
+	}
+}
+
+func TestFontDrawStringBox(t *testing.T) {
+	f := testFont()
+	dst := image.NewGray(image.Rect(0, 0, 40, 20))
+	box := image.Rect(2, 1, 14, 11)
+
+	// wraps to "abc", "def" and "ghi", and the last line is below the box
+	f.DrawStringBox(dst, box, "abc def ghi", color.White)
+
+	expected := image.NewGray(dst.Rect)
+	f.DrawString(expected, box.Min, "abc\ndef", color.White)
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			e := expected.GrayAt(x, y)
+			if !(image.Point{X: x, Y: y}).In(box) {
+				e.Y = 0
+			}
+			if c := dst.GrayAt(x, y); c != e {
+				t.Fatalf("%d,%d: got %d, expected %d", x, y, c.Y, e.Y)
+			}
+		}
+	}
+	if dst.GrayAt(2, 6).Y != 0xFF {
+		t.Error("the second line was not drawn")
+	}
+}
+
+func TestFontDraw(t *testing.T) {
+	f := testFont()
+
+	direct := image.NewGray(image.Rect(0, 0, 20, 10))
+	f.DrawString(direct, image.Point{X: 1, Y: 1}, "a b", color.White)
+
+	face := image.NewGray(image.Rect(0, 0, 20, 10))
+	d := font.Drawer{
+		Dst:  face,
+		Src:  image.White,
+		Face: f.Face(),
+		Dot:  fixed.P(1, 1+int(f.LineHeight)),
+	}
+	d.DrawString("a b")
+
+	if !reflect.DeepEqual(direct.Pix, face.Pix) {
+		t.Errorf("font.Face and DrawString differ")
+	}
+	if direct.GrayAt(1, 1).Y != 0xFF || direct.GrayAt(2, 2).Y != 0 {
+		t.Errorf("character drawn incorrectly")
+	}
+	if d.Dot.X != fixed.I(1+3+2+3) {
+		t.Errorf("got advance %v", d.Dot.X)
+	}
+}
+
+func TestFontConversions(t *testing.T) {
+	f := testFont()
+	f.Characters['A'] = NewCharacter(9, 7)
+	for i := 0; i < 9; i++ {
+		f.Characters['A'].Set(i, i%7, true)
+	}
+
+	b, err := f.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := NewFont(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, f) {
+		t.Errorf("font changed after encoding")
+	}
+
+	var bdf bytes.Buffer
+	if err := f.WriteBDF(&bdf, "test"); err != nil {
+		t.Fatal(err)
+	}
+	fromBDF, err := ReadBDF(&bdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromBDF, f) {
+		t.Errorf("font changed after converting to BDF")
+	}
+
+	var img, metrics bytes.Buffer
+	if err := f.Atlas(16).Encode(&img, &metrics); err != nil {
+		t.Fatal(err)
+	}
+	atlas, err := DecodeFontAtlas(&img, &metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromAtlas, err := atlas.Font()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromAtlas, f) {
+		t.Errorf("font changed after converting to an atlas")
+	}
+}
@@ -0,0 +1,172 @@
+package resource
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/32bitkid/sci/screen"
+)
+
+// NewDitheredSprite converts a truecolor image into a cel, by choosing the
+// dithered pair that best matches each pixel. Transparent pixels are given the
+// AutoKeyColor. The ditherer defaults to screen.DefaultDitherers.EGA, and must
+// only produce EGA colors.
+func NewDitheredSprite(img image.Image, d *screen.Ditherer, x, y int8) (Sprite, error) {
+	if d == nil {
+		d = screen.DefaultDitherers.EGA
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() > 0xFFFF || bounds.Dy() > 0xFFFF {
+		return Sprite{}, fmt.Errorf("image is too large")
+	}
+
+	pairs := d.Quantize(img)
+	sprite := Sprite{
+		SpriteHeader: SpriteHeader{
+			Width:    uint16(bounds.Dx()),
+			Height:   uint16(bounds.Dy()),
+			X:        x,
+			Y:        y,
+			KeyColor: AutoKeyColor,
+		},
+		Pixels: make([]uint8, 0, bounds.Dx()*bounds.Dy()),
+	}
+
+	for py := 0; py < bounds.Dy(); py++ {
+		for px := 0; px < bounds.Dx(); px++ {
+			at := bounds.Min.Add(image.Point{X: px, Y: py})
+			if _, _, _, a := img.At(at.X, at.Y).RGBA(); a < 0x8000 {
+				sprite.Pixels = append(sprite.Pixels, AutoKeyColor)
+				continue
+			}
+			c := d.DitherAt(px, py, pairs.ColorIndexAt(at.X, at.Y))
+			if c > 0xF {
+				return Sprite{}, fmt.Errorf("color %d at %d,%d is not an EGA color", c, at.X, at.Y)
+			}
+			sprite.Pixels = append(sprite.Pixels, c)
+		}
+	}
+	return sprite, nil
+}
+
+// white is the dithered pair of the background of a picture. Fills only
+// spread over pixels of this color.
+const white = 0xFF
+
+// PicCommandsFromImage converts a truecolor image into the commands of a
+// picture, by choosing the dithered pair that best matches each pixel. The
+// top-left 320x190 pixels of the image are used, and transparent pixels are
+// left as the white background.
+//
+// Each area of a single dithered pair is drawn by outlining it with single
+// pixel patterns, then filling its inside. Areas of a pair that includes
+// white cannot be filled, and are drawn one pixel at a time.
+func PicCommandsFromImage(img image.Image, d *screen.Ditherer) []PicCommand {
+	if d == nil {
+		d = screen.DefaultDitherers.EGA
+	}
+
+	bounds := img.Bounds()
+	size := image.Rect(0, 0, 320, 190).Intersect(bounds.Sub(bounds.Min))
+	width, height := size.Dx(), size.Dy()
+
+	quantized := d.Quantize(img)
+	pairs := make([]uint8, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			at := bounds.Min.Add(image.Point{X: x, Y: y})
+			if _, _, _, a := img.At(at.X, at.Y).RGBA(); a < 0x8000 {
+				pairs[y*width+x] = white
+			} else {
+				pairs[y*width+x] = quantized.ColorIndexAt(at.X, at.Y)
+			}
+		}
+	}
+
+	pairAt := func(x, y int) (uint8, bool) {
+		if x < 0 || y < 0 || x >= width || y >= height {
+			return 0, false
+		}
+		return pairs[y*width+x], true
+	}
+
+	// an outline pixel is one with a neighbor of a different pair
+	outline := func(x, y int) bool {
+		c, _ := pairAt(x, y)
+		for _, n := range [...]image.Point{{X: 0, Y: -1}, {X: 0, Y: 1}, {X: -1, Y: 0}, {X: 1, Y: 0}} {
+			if nc, ok := pairAt(x+n.X, y+n.Y); !ok || nc != c {
+				return true
+			}
+		}
+		return false
+	}
+
+	type area struct {
+		patterns []PicPatternPoint
+		fills    []image.Point
+	}
+	var (
+		areas = map[uint8]*area{}
+		order []uint8
+		seen  = make([]bool, width*height)
+	)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := pairs[y*width+x]
+			if c == white {
+				continue
+			}
+			a, ok := areas[c]
+			if !ok {
+				a = &area{}
+				areas[c] = a
+				order = append(order, c)
+			}
+
+			hasWhite := c&0xF == 0xF || c>>4 == 0xF
+			switch {
+			case hasWhite:
+				if d.DitherAt(x, y, c) != 0xF {
+					a.patterns = append(a.patterns, PicPatternPoint{Point: image.Point{X: x, Y: y}})
+				}
+			case outline(x, y):
+				a.patterns = append(a.patterns, PicPatternPoint{Point: image.Point{X: x, Y: y}})
+			case !seen[y*width+x]:
+				// mark the inside that a fill from here will reach
+				a.fills = append(a.fills, image.Point{X: x, Y: y})
+				stack := []image.Point{{X: x, Y: y}}
+				seen[y*width+x] = true
+				for len(stack) > 0 {
+					p := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					for _, n := range [...]image.Point{{X: 0, Y: -1}, {X: 0, Y: 1}, {X: -1, Y: 0}, {X: 1, Y: 0}} {
+						q := p.Add(n)
+						if nc, ok := pairAt(q.X, q.Y); !ok || nc != c || seen[q.Y*width+q.X] || outline(q.X, q.Y) {
+							continue
+						}
+						seen[q.Y*width+q.X] = true
+						stack = append(stack, q)
+					}
+				}
+			}
+		}
+	}
+
+	var cmds []PicCommand
+	for _, c := range order {
+		a := areas[c]
+		cmds = append(cmds,
+			PicUpdatePalette{Entries: []PicPaletteEntry{{Index: 0, Color: c}}},
+			PicSetVisual{Color: 0},
+		)
+		if len(a.patterns) > 0 {
+			cmds = append(cmds, PicPattern{Points: a.patterns})
+		}
+		if len(a.fills) > 0 {
+			cmds = append(cmds, PicFill{Points: a.fills})
+		}
+	}
+	return append(cmds, PicDone{})
+}
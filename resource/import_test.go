@@ -0,0 +1,76 @@
+package resource
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/32bitkid/sci/screen"
+)
+
+func TestPicCommandsFromImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 320, 190))
+	for y := 0; y < 190; y++ {
+		for x := 0; x < 320; x++ {
+			var c color.NRGBA
+			switch {
+			case x < 100:
+				c = color.NRGBA{R: uint8(x * 2), G: uint8(y), B: 0x80, A: 0xFF}
+			case (x-200)*(x-200)+(y-95)*(y-95) < 50*50:
+				c = color.NRGBA{R: 0xCC, G: 0x44, B: 0x22, A: 0xFF}
+			case y > 150:
+				c = color.NRGBA{R: 0xDD, G: 0xDD, B: 0xFF, A: 0xFF}
+			case y < 20:
+				c = color.NRGBA{}
+			default:
+				c = color.NRGBA{R: 0x10, G: 0x60, B: 0x20, A: 0xFF}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	d := screen.DefaultDitherers.EGA
+	b, err := EncodePicCommands(PicCommandsFromImage(img, d))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pic, err := NewPic(b, PicOptions{Scaler: screen.Scaler1x1{Ditherer: d}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	visual := pic.Visual().Image().(*image.Paletted)
+
+	pairs := d.Quantize(img)
+	for y := 0; y < 190; y++ {
+		for x := 0; x < 320; x++ {
+			expected := uint8(0xF)
+			if img.NRGBAAt(x, y).A != 0 {
+				expected = d.DitherAt(x, y, pairs.ColorIndexAt(x, y))
+			}
+			if actual := visual.ColorIndexAt(x, y); actual != expected {
+				t.Fatalf("got color %d at %d,%d, expected %d", actual, x, y, expected)
+			}
+		}
+	}
+}
+
+func TestEncodeLargeImport(t *testing.T) {
+	// every pixel of a checkerboard is an area of its own
+	img := image.NewPaletted(image.Rect(0, 0, 320, 190), screen.DefaultPalettes.EGA)
+	for y := 0; y < 190; y++ {
+		for x := 0; x < 320; x++ {
+			img.SetColorIndex(x, y, uint8(1+(x+y)%2*3))
+		}
+	}
+	cmds := PicCommandsFromImage(img, nil)
+
+	start := time.Now()
+	if _, err := EncodePicCommands(cmds); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("encoding took %v", elapsed)
+	}
+}
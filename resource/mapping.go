@@ -22,6 +22,16 @@ func (pic PictureMapping) Render(options ...PicOptions) (screen.Pic, error) {
 	return NewPic(res.Bytes(), options...)
 }
 
+// RenderState draws the picture, like Render, and returns the state that it
+// finished drawing with, see NewPicState.
+func (pic PictureMapping) RenderState(options ...PicOptions) (*PicState, error) {
+	res, err := pic.Resource()
+	if err != nil {
+		return nil, err
+	}
+	return NewPicState(res.Bytes(), options...)
+}
+
 type ViewMapping struct{ Mapping }
 
 func (view ViewMapping) Render() (View, error) {
@@ -61,3 +71,13 @@ func (view CursorMapping) Cursor() (Cursor, error) {
 	}
 	return NewCursor(res.Bytes())
 }
+
+type MemoryMapping struct{ Mapping }
+
+func (m MemoryMapping) Memory() (Memory, error) {
+	res, err := m.Resource()
+	if err != nil {
+		return nil, err
+	}
+	return NewMemory(res.Bytes())
+}
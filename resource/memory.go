@@ -0,0 +1,46 @@
+package resource
+
+import "errors"
+
+// Memory is an image of the interpreter's heap.
+type Memory []byte
+
+func NewMemory(b []byte) (Memory, error) {
+	if len(b) > 0xFFFF {
+		return nil, errors.New("memory is larger than the heap")
+	}
+	return append(Memory(nil), b...), nil
+}
+
+// Word returns the little-endian word at address, or 0 if it is outside of
+// the image.
+func (m Memory) Word(address uint16) uint16 {
+	a := int(address)
+	if a+1 >= len(m) {
+		return 0
+	}
+	return uint16(m[a]) | uint16(m[a+1])<<8
+}
+
+// SetWord changes the little-endian word at address, if it is inside of the
+// image.
+func (m Memory) SetWord(address uint16, v uint16) {
+	a := int(address)
+	if a+1 >= len(m) {
+		return
+	}
+	m[a], m[a+1] = uint8(v), uint8(v>>8)
+}
+
+// StringAt returns the NUL-terminated string at address.
+func (m Memory) StringAt(address uint16) string {
+	a := int(address)
+	if a >= len(m) {
+		return ""
+	}
+	end := a
+	for end < len(m) && m[end] != 0x00 {
+		end++
+	}
+	return string(m[a:end])
+}
@@ -4,40 +4,121 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"image"
+	"io"
 
 	"github.com/32bitkid/bitreader"
 	"github.com/32bitkid/sci/screen"
 )
 
+// NewPic decodes and draws a picture resource.
 func NewPic(b []byte, options ...PicOptions) (screen.Pic, error) {
-	var debugFn DebugCallback
-	var scaler screen.Scaler = &screen.Scaler1x1{
-		Ditherer: screen.DefaultDitherers.EGA,
+	state, err := NewPicState(b, options...)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// NewPicState decodes and draws a picture resource, like NewPic. The
+// *PicState that it returns also holds the state that the picture finished
+// drawing with, such as its priority bands and monochrome palettes and codes.
+func NewPicState(b []byte, options ...PicOptions) (*PicState, error) {
+	opts, err := resolvePicOptions(options)
+	if err != nil {
+		return nil, err
 	}
+	return readPic(b, opts)
+}
+
+// resolvePicOptions merges options, with later options taking precedence,
+// and fills in the mode and its Scaler when they are not given. There is no
+// Scaler for the CGA and monochrome modes, as the tables of their drivers
+// are not included.
+func resolvePicOptions(options []PicOptions) (PicOptions, error) {
+	var resolved PicOptions
 	for _, opts := range options {
 		if opts.Scaler != nil {
-			scaler = opts.Scaler
+			resolved.Scaler = opts.Scaler
 		}
 
 		if opts.DebugFn != nil {
-			debugFn = opts.DebugFn
+			resolved.DebugFn = opts.DebugFn
+		}
+
+		if opts.Mode != PicModeDefault {
+			resolved.Mode = opts.Mode
 		}
 	}
 
-	return readPic(b, scaler, debugFn)
+	if resolved.Mode == PicModeDefault {
+		resolved.Mode = PicModeEGA
+	}
+
+	if resolved.Scaler == nil {
+		if resolved.Mode != PicModeEGA {
+			return resolved, errors.New("a Scaler with the driver's Ditherer is needed for CGA and monochrome pictures")
+		}
+		resolved.Scaler = &screen.Scaler1x1{Ditherer: screen.DefaultDitherers.EGA}
+	}
+	return resolved, nil
 }
 
 type PicOptions struct {
 	screen.Scaler
 	DebugFn DebugCallback
+	Mode    PicMode
 }
 
+// PicMode is the kind of display that a picture is drawn for.
+type PicMode uint8
+
+const (
+	// PicModeDefault leaves the mode to the other options, and is
+	// PicModeEGA when none of them set it.
+	PicModeDefault PicMode = iota
+	PicModeEGA
+	// PicModeCGA draws the EGA colors of the picture. It needs a Scaler
+	// with the table of the CGA driver, see screen.NewDriverDitherer.
+	PicModeCGA
+	// PicModeMono draws with the monochrome palettes of the picture, and with
+	// its monochrome visual and priority where it sets them. It needs a
+	// Scaler with the table of the monochrome driver.
+	PicModeMono
+)
+
 type DebugCallback func(*PicState)
 
 type picReader struct {
-	bits bitreader.BitReader
+	bits   bitreader.BitReader
+	offset int
+}
+
+func (p *picReader) read8() (uint8, error) {
+	v, err := p.bits.Read8(8)
+	if err != nil {
+		return 0, err
+	}
+	p.offset++
+	return v, nil
+}
+
+func (p *picReader) read(b []byte) error {
+	n, err := io.ReadFull(p.bits, b)
+	p.offset += n
+	return err
+}
+
+// atOpCode reports whether the next byte in the bit-stream is an op-code,
+// which terminates the argument list of the current op-code.
+func (p *picReader) atOpCode() (bool, error) {
+	peek, err := p.bits.Peek8(8)
+	if err != nil {
+		return false, err
+	}
+	return peek >= 0xf0, nil
 }
 
 // getPoint24 gets reads an absolute position from the
@@ -49,11 +130,12 @@ type picReader struct {
 //  8-15 | low byte of x-position
 // 16-23 | low byte of y-position
 //
-func (p picReader) getPoint24() (int, int, error) {
+func (p *picReader) getPoint24() (int, int, error) {
 	code, err := p.bits.Read32(24)
 	if err != nil {
 		return 0, 0, err
 	}
+	p.offset += 3
 	x := ((code & 0xF00000) >> 12) | ((code & 0xFF00) >> 8)
 	y := ((code & 0x0F0000) >> 8) | ((code & 0x00FF) >> 0)
 	return int(x), int(y), nil
@@ -66,8 +148,8 @@ func (p picReader) getPoint24() (int, int, error) {
 // 0-7  | y-delta
 // 8-15 | x-delta
 //
-func (p picReader) getPoint16(x, y int) (int, int, error) {
-	dy, err := p.bits.Read8(8)
+func (p *picReader) getPoint16(x, y int) (int, int, error) {
+	dy, err := p.read8()
 	if err != nil {
 		return 0, 0, err
 	}
@@ -78,7 +160,7 @@ func (p picReader) getPoint16(x, y int) (int, int, error) {
 		y += int(dy & 0x7F)
 	}
 
-	dx, err := p.bits.Read8(8)
+	dx, err := p.read8()
 	if err != nil {
 		return 0, 0, err
 	}
@@ -96,8 +178,8 @@ func (p picReader) getPoint16(x, y int) (int, int, error) {
 // 0-3  | y-delta
 // 4-7  | x-delta
 //
-func (p picReader) getPoint8(x, y int) (int, int, error) {
-	code, err := p.bits.Read8(8)
+func (p *picReader) getPoint8(x, y int) (int, int, error) {
+	code, err := p.read8()
 	if err != nil {
 		return 0, 0, err
 	}
@@ -119,6 +201,83 @@ func (p picReader) getPoint8(x, y int) (int, int, error) {
 	return x, y, nil
 }
 
+func (p *picReader) getPoint24Delta(_, _ int) (int, int, error) {
+	return p.getPoint24()
+}
+
+// getLinePoints reads the vertices of a line. The first vertex is always
+// absolute, the remaining vertices are read with delta.
+func (p *picReader) getLinePoints(delta func(x, y int) (int, int, error)) ([]image.Point, error) {
+	x, y, err := p.getPoint24()
+	if err != nil {
+		return nil, err
+	}
+
+	points := []image.Point{{X: x, Y: y}}
+	for {
+		if done, err := p.atOpCode(); err != nil {
+			return nil, err
+		} else if done {
+			return points, nil
+		}
+
+		x, y, err = delta(x, y)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, image.Point{X: x, Y: y})
+	}
+}
+
+// getTexture reads the texture of the next pattern, if the pattern is textured.
+func (p *picReader) getTexture(code PatternCode) (uint8, error) {
+	if code.IsSolid() {
+		return 0, nil
+	}
+	texture, err := p.read8()
+	if err != nil {
+		return 0, err
+	}
+	return texture >> 1, nil
+}
+
+// getPatternPoints reads the positions, and textures, of a list of patterns.
+// When delta is nil, every position is absolute. Otherwise only the first
+// position is absolute, and the remaining positions are read with delta.
+func (p *picReader) getPatternPoints(code PatternCode, delta func(x, y int) (int, int, error)) ([]PicPatternPoint, error) {
+	var points []PicPatternPoint
+	for {
+		if len(points) > 0 || delta == nil {
+			if done, err := p.atOpCode(); err != nil {
+				return nil, err
+			} else if done {
+				return points, nil
+			}
+		}
+
+		texture, err := p.getTexture(code)
+		if err != nil {
+			return nil, err
+		}
+
+		var x, y int
+		if len(points) == 0 || delta == nil {
+			x, y, err = p.getPoint24()
+		} else {
+			last := points[len(points)-1]
+			x, y, err = delta(last.X, last.Y)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		points = append(points, PicPatternPoint{
+			Point:   image.Point{X: x, Y: y},
+			Texture: texture,
+		})
+	}
+}
+
 // Picture Op-Codes
 type pOpCode uint8
 
@@ -154,14 +313,21 @@ const (
 type pOpxCode uint8
 
 const (
-	pOpxUpdatePalette pOpxCode = 0x00
-	pOpxSetPalette             = 0x01
+	pOpxUpdatePalette       pOpxCode = 0x00
+	pOpxSetPalette                   = 0x01
+	pOpxSetMonoPalette               = 0x02
+	pOpxSetMonoVisual                = 0x03
+	pOpxDisableMonoVisual            = 0x04
+	pOpxSetMonoPriority              = 0x05
+	pOpxDisableMonoPriority          = 0x06
+	pOpxEmbeddedCel                  = 0x07
+	pOpxSetPriorityBands             = 0x08
 )
 
-// picPalette is an array of 40 uint8 values, which is actually a tuple of two 4-bit EGA colors.
-type picPalette [40]uint8
+// PicPalette is an array of 40 uint8 values, which is actually a tuple of two 4-bit EGA colors.
+type PicPalette [40]uint8
 
-var defaultPalette = picPalette{
+var defaultPalette = PicPalette{
 	0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77,
 	0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0x88,
 	0x88, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x88,
@@ -169,15 +335,16 @@ var defaultPalette = picPalette{
 	0x08, 0x91, 0x2a, 0x3b, 0x4c, 0x5d, 0x6e, 0x88,
 }
 
-type picDrawMode uint
+// PicDrawMode is the set of layers that commands are drawn to.
+type PicDrawMode uint
 
 const (
-	picDrawVisual   picDrawMode = 1
-	picDrawPriority             = 2
-	picDrawControl              = 4
+	PicDrawVisual PicDrawMode = 1 << iota
+	PicDrawPriority
+	PicDrawControl
 )
 
-func (mode *picDrawMode) set(flag picDrawMode, enabled bool) {
+func (mode *PicDrawMode) set(flag PicDrawMode, enabled bool) {
 	if enabled {
 		*mode |= flag
 	} else {
@@ -185,27 +352,32 @@ func (mode *picDrawMode) set(flag picDrawMode, enabled bool) {
 	}
 }
 
-func (mode picDrawMode) has(flag picDrawMode) bool {
+// Has reports whether drawing to the layer flag is enabled.
+func (mode PicDrawMode) Has(flag PicDrawMode) bool {
 	return mode&flag == flag
 }
 
-type patternCode uint8
+// PatternCode describes the brush used to draw patterns.
+type PatternCode uint8
 
-func (code patternCode) size() int {
+// Size is the radius of the brush.
+func (code PatternCode) Size() int {
 	return int(code & 0x7)
 }
 
-func (code patternCode) isRect() bool {
+// IsRect reports whether the brush is a rectangle, rather than a circle.
+func (code PatternCode) IsRect() bool {
 	return code&0x10 != 0
 }
 
-func (code patternCode) isSolid() bool {
+// IsSolid reports whether the brush is solid, rather than textured.
+func (code PatternCode) IsSolid() bool {
 	return code&0x20 == 0
 }
 
 type colorCode uint8
 
-func (code colorCode) color(palettes [4]picPalette) uint8 {
+func (code colorCode) color(palettes [4]PicPalette) uint8 {
 	pal, idx := int(code)/40, int(code)%40
 	return palettes[pal][idx]
 }
@@ -219,17 +391,147 @@ func (code nybbleCode) code() uint8 {
 type PicState struct {
 	screen.Pic
 
-	palettes [4]picPalette
-	drawMode picDrawMode
+	palettes [4]PicPalette
+	drawMode PicDrawMode
 
 	colorCode    colorCode
 	priorityCode nybbleCode
 	controlCode  nybbleCode
-	patternCode  patternCode
+	patternCode  PatternCode
+
+	// Monochrome displays have their own palettes and codes, which
+	// are carried alongside the EGA ones in the OPX commands.
+	monoPalettes     [4]PicPalette
+	monoDrawMode     PicDrawMode
+	monoColorCode    colorCode
+	monoPriorityCode nybbleCode
 
+	priorityBands PriorityBands
+
+	mode    PicMode
 	debugFn DebugCallback
 }
 
+var _ screen.Pic = (*PicState)(nil)
+
+// PicSnapshot is a copy of the drawing state of a picture.
+type PicSnapshot struct {
+	DrawMode PicDrawMode
+	Palettes [4]PicPalette
+
+	// ColorCode is the index of the current visual color in Palettes, and
+	// Color is the pair of EGA colors that it resolves to.
+	ColorCode uint8
+	Color     uint8
+
+	PriorityCode uint8
+	ControlCode  uint8
+	PatternCode  PatternCode
+}
+
+// EGA returns the two EGA colors that are dithered together to draw Color.
+func (s PicSnapshot) EGA() (uint8, uint8) {
+	return s.Color & 0xF, s.Color >> 4
+}
+
+// Snapshot returns a copy of the current drawing state.
+func (s *PicState) Snapshot() PicSnapshot {
+	return PicSnapshot{
+		DrawMode:     s.DrawMode(),
+		Palettes:     s.Palettes(),
+		ColorCode:    s.ColorCode(),
+		Color:        s.Color(),
+		PriorityCode: s.PriorityCode(),
+		ControlCode:  s.ControlCode(),
+		PatternCode:  s.PatternCode(),
+	}
+}
+
+// DrawMode returns the layers that are currently being drawn to.
+func (s *PicState) DrawMode() PicDrawMode {
+	return s.drawMode
+}
+
+// Palettes returns the current palettes.
+func (s *PicState) Palettes() [4]PicPalette {
+	return s.palettes
+}
+
+// ColorCode returns the index of the current visual color in the palettes.
+func (s *PicState) ColorCode() uint8 {
+	return uint8(s.colorCode)
+}
+
+// Color returns the pair of EGA colors for the current visual color. The low
+// nybble holds the first color, and the high nybble the second.
+func (s *PicState) Color() uint8 {
+	return s.colorCode.color(s.palettes)
+}
+
+// PriorityCode returns the current priority.
+func (s *PicState) PriorityCode() uint8 {
+	return s.priorityCode.code()
+}
+
+// ControlCode returns the current control color.
+func (s *PicState) ControlCode() uint8 {
+	return s.controlCode.code()
+}
+
+// PatternCode returns the current brush.
+func (s *PicState) PatternCode() PatternCode {
+	return s.patternCode
+}
+
+// PriorityBands returns the priority bands defined by the picture, or the
+// DefaultPriorityBands if it did not define any.
+func (s *PicState) PriorityBands() PriorityBands {
+	return s.priorityBands
+}
+
+// MonoPalettes returns the palettes used when drawing on a monochrome display.
+func (s *PicState) MonoPalettes() [4]PicPalette {
+	return s.monoPalettes
+}
+
+// MonoColorCode returns the index of the visual color that monochrome
+// displays use in the mono palettes, and whether it is set. When it is not
+// set, they use ColorCode.
+func (s *PicState) MonoColorCode() (uint8, bool) {
+	return uint8(s.monoColorCode), s.monoDrawMode.Has(PicDrawVisual)
+}
+
+// MonoColor returns the pair of colors of MonoColorCode, from the mono
+// palettes.
+func (s *PicState) MonoColor() uint8 {
+	return s.monoColorCode.color(s.monoPalettes)
+}
+
+// MonoPriorityCode returns the priority that monochrome displays use, and
+// whether it is set. When it is not set, they use PriorityCode.
+func (s *PicState) MonoPriorityCode() (uint8, bool) {
+	return s.monoPriorityCode.code(), s.monoDrawMode.Has(PicDrawPriority)
+}
+
+// visual returns the color that the visual layer is drawn with.
+func (s *PicState) visual() uint8 {
+	if s.mode != PicModeMono {
+		return s.colorCode.color(s.palettes)
+	}
+	if s.monoDrawMode.Has(PicDrawVisual) {
+		return s.monoColorCode.color(s.monoPalettes)
+	}
+	return s.colorCode.color(s.monoPalettes)
+}
+
+// priority returns the code that the priority layer is drawn with.
+func (s *PicState) priority() uint8 {
+	if s.mode == PicModeMono && s.monoDrawMode.Has(PicDrawPriority) {
+		return s.monoPriorityCode.code()
+	}
+	return s.priorityCode.code()
+}
+
 func (s *PicState) debugger() {
 	if s.debugFn != nil {
 		s.debugFn(s)
@@ -238,8 +540,8 @@ func (s *PicState) debugger() {
 
 func (s *PicState) fill(cx, cy int) {
 	switch {
-	case s.drawMode.has(picDrawVisual):
-		color := s.colorCode.color(s.palettes)
+	case s.drawMode.Has(PicDrawVisual):
+		color := s.visual()
 		if color == 255 {
 			// FIXME this fill occurs but it doesn't make any sense.
 			//  It's asking for a solid white fill, but that should be a noop if legalColor is always 15.
@@ -247,13 +549,13 @@ func (s *PicState) fill(cx, cy int) {
 		}
 		s.Visual().Fill(cx, cy, 0xf, color)
 		s.debugger()
-	case s.drawMode.has(picDrawPriority):
-		code := s.priorityCode.code()
+	case s.drawMode.Has(PicDrawPriority):
+		code := s.priority()
 		if code == 0 {
 			return
 		}
 		s.Priority().Fill(cx, cy, 0x0, code)
-	case s.drawMode.has(picDrawControl):
+	case s.drawMode.Has(PicDrawControl):
 		code := s.controlCode.code()
 		if code == 0 {
 			return
@@ -265,175 +567,154 @@ func (s *PicState) fill(cx, cy int) {
 }
 
 func (s *PicState) line(x1, y1, x2, y2 int) {
-	if s.drawMode.has(picDrawVisual) {
-		color := s.colorCode.color(s.palettes)
+	if s.drawMode.Has(PicDrawVisual) {
+		color := s.visual()
 		s.Visual().Line(x1, y1, x2, y2, color)
 		s.debugger()
 	}
-	if s.drawMode.has(picDrawPriority) {
-		code := s.priorityCode.code()
+	if s.drawMode.Has(PicDrawPriority) {
+		code := s.priority()
 		s.Priority().Line(x1, y1, x2, y2, code)
 	}
-	if s.drawMode.has(picDrawControl) {
+	if s.drawMode.Has(PicDrawControl) {
 		code := s.controlCode.code()
 		s.Control().Line(x1, y1, x2, y2, code)
 	}
 }
 
 func (s *PicState) drawPattern(cx, cy int, patternTexture uint8) {
-	size := s.patternCode.size()
-	isRect := s.patternCode.isRect()
-	isSolid := s.patternCode.isSolid()
+	size := s.patternCode.Size()
+	isRect := s.patternCode.IsRect()
+	isSolid := s.patternCode.IsSolid()
 
-	if s.drawMode.has(picDrawVisual) {
-		color := s.colorCode.color(s.palettes)
+	if s.drawMode.Has(PicDrawVisual) {
+		color := s.visual()
 		s.Visual().Pattern(cx, cy, size, isRect, isSolid, patternTexture, color)
 		s.debugger()
 	}
-	if s.drawMode.has(picDrawPriority) {
-		code := s.priorityCode.code()
+	if s.drawMode.Has(PicDrawPriority) {
+		code := s.priority()
 		s.Priority().Pattern(cx, cy, size, isRect, isSolid, patternTexture, code)
 	}
-	if s.drawMode.has(picDrawControl) {
-		code := s.priorityCode.code()
+	if s.drawMode.Has(PicDrawControl) {
+		code := s.priority()
 		s.Control().Pattern(cx, cy, size, isRect, isSolid, patternTexture, code)
 	}
 }
 
-func readPic(
-	payload []byte,
-	scaler screen.Scaler,
-	debugFn DebugCallback,
-) (screen.Pic, error) {
-	r := picReader{
-		bitreader.NewReader(bufio.NewReader(bytes.NewReader(payload))),
+// drawCel draws a cel that is embedded in the picture with its top-left
+// corner at x, y. The cel is always drawn onto the visual layer, and onto
+// the priority layer when it is enabled.
+func (s *PicState) drawCel(x, y int, cel Sprite) {
+	width, height := int(cel.Width), int(cel.Height)
+	for cy := 0; cy < height; cy++ {
+		for cx := 0; cx < width; cx++ {
+			c := cel.Pixels[cy*width+cx]
+			if c == cel.KeyColor {
+				continue
+			}
+			screen.Plot(s.Visual(), x+cx, y+cy, c<<4|c)
+			if s.drawMode.Has(PicDrawPriority) {
+				screen.Plot(s.Priority(), x+cx, y+cy, s.priority())
+			}
+		}
 	}
+	s.debugger()
+}
 
-	bounds := image.Rect(0, 0, 320, 190)
+// readEmbeddedCel decodes the payload of an embedded cel. It uses the same
+// header and run-length encoding as a view cel, but the header is padded to
+// 8 bytes.
+func readEmbeddedCel(b []byte) (Sprite, error) {
+	r := bytes.NewReader(b)
 
-	var state = PicState{
-		Pic:      scaler.NewPic(bounds),
-		drawMode: picDrawVisual | picDrawPriority,
-		palettes: [4]picPalette{
-			defaultPalette,
-			defaultPalette,
-			defaultPalette,
-			defaultPalette,
-		},
-		debugFn: debugFn,
+	var cel Sprite
+	if err := binary.Read(r, binary.LittleEndian, &cel.SpriteHeader); err != nil {
+		return cel, err
 	}
 
-	state.Visual().Clear(0xFF)
-	state.Control().Clear(0x0)
-	state.Priority().Clear(0x0)
+	if _, err := r.Seek(1, io.SeekCurrent); err != nil {
+		return cel, err
+	}
+
+	pixels, err := readSpritePixels(r, int(cel.Width)*int(cel.Height))
+	if err != nil {
+		return cel, err
+	}
+	cel.Pixels = pixels
+	return cel, nil
+}
+
+// DecodePicCommands decodes the op-code stream of a picture resource into
+// a list of commands, without drawing any of them.
+func DecodePicCommands(b []byte) ([]PicCommand, error) {
+	r := &picReader{
+		bits: bitreader.NewReader(bufio.NewReader(bytes.NewReader(b))),
+	}
+
+	var (
+		cmds    []PicCommand
+		pattern PatternCode
+	)
 
-opLoop:
 	for {
-		op, err := r.bits.Read8(8)
+		at := PicOffset(r.offset)
+		op, err := r.read8()
 		if err != nil {
 			return nil, err
 		}
 
 		switch pOpCode(op) {
 		case pOpSetVisual:
-			code, err := r.bits.Read8(8)
+			code, err := r.read8()
 			if err != nil {
 				return nil, err
 			}
-
-			state.colorCode = colorCode(code)
-			state.drawMode.set(picDrawVisual, true)
+			cmds = append(cmds, PicSetVisual{PicOffset: at, Color: code})
 		case pOpDisableVisual:
-			state.drawMode.set(picDrawVisual, false)
+			cmds = append(cmds, PicDisableVisual{PicOffset: at})
 
 		case pOpSetPriority:
-			code, err := r.bits.Read8(8)
+			code, err := r.read8()
 			if err != nil {
 				return nil, err
 			}
-			state.priorityCode = nybbleCode(code)
-			state.drawMode.set(picDrawPriority, true)
+			cmds = append(cmds, PicSetPriority{PicOffset: at, Priority: code})
 		case pOpDisablePriority:
-			state.drawMode.set(picDrawPriority, false)
+			cmds = append(cmds, PicDisablePriority{PicOffset: at})
 
 		case pOpSetControl:
-			code, err := r.bits.Read8(8)
+			code, err := r.read8()
 			if err != nil {
 				return nil, err
 			}
-			state.controlCode = nybbleCode(code)
-			state.drawMode.set(picDrawControl, true)
+			cmds = append(cmds, PicSetControl{PicOffset: at, Control: code})
 		case pOpDisableControl:
-			state.drawMode.set(picDrawControl, false)
+			cmds = append(cmds, PicDisableControl{PicOffset: at})
 
 		// Lines
-		case pOpShortLines:
-			x1, y1, err := r.getPoint24()
-			if err != nil {
-				return nil, err
+		case pOpShortLines, pOpMediumLines, pOpLongLines:
+			delta := r.getPoint24Delta
+			switch op {
+			case pOpShortLines:
+				delta = r.getPoint8
+			case pOpMediumLines:
+				delta = r.getPoint16
 			}
-			for {
-				if peek, err := r.bits.Peek8(8); err != nil {
-					return nil, err
-				} else if peek >= 0xf0 {
-					break
-				}
 
-				x2, y2, err := r.getPoint8(x1, y1)
-				if err != nil {
-					return nil, err
-				}
-
-				state.line(x1, y1, x2, y2)
-				x1, y1 = x2, y2
-			}
-		case pOpMediumLines:
-			x1, y1, err := r.getPoint24()
-			if err != nil {
-				return nil, err
-			}
-			for {
-				if peek, err := r.bits.Peek8(8); err != nil {
-					return nil, err
-				} else if peek >= 0xf0 {
-					break
-				}
-
-				x2, y2, err := r.getPoint16(x1, y1)
-				if err != nil {
-					return nil, err
-				}
-
-				state.line(x1, y1, x2, y2)
-				x1, y1 = x2, y2
-			}
-		case pOpLongLines:
-			x1, y1, err := r.getPoint24()
+			points, err := r.getLinePoints(delta)
 			if err != nil {
 				return nil, err
 			}
-			for {
-				if peek, err := r.bits.Peek8(8); err != nil {
-					return nil, err
-				} else if peek >= 0xf0 {
-					break
-				}
-
-				x2, y2, err := r.getPoint24()
-				if err != nil {
-					return nil, err
-				}
-
-				state.line(x1, y1, x2, y2)
-				x1, y1 = x2, y2
-			}
+			cmds = append(cmds, PicLine{PicOffset: at, Points: points})
 
 		// Fills
 		case pOpFills:
+			var points []image.Point
 			for {
-				if peek, err := r.bits.Peek8(8); err != nil {
+				if done, err := r.atOpCode(); err != nil {
 					return nil, err
-				} else if peek >= 0xf0 {
+				} else if done {
 					break
 				}
 
@@ -441,217 +722,185 @@ opLoop:
 				if err != nil {
 					return nil, err
 				}
-
-				state.fill(x, y)
+				points = append(points, image.Point{X: x, Y: y})
 			}
+			cmds = append(cmds, PicFill{PicOffset: at, Points: points})
 
 		// Patterns
 		case pOpSetPattern:
-			code, err := r.bits.Read8(8)
-			if err != nil {
-				return nil, err
-			}
-			state.patternCode = patternCode(code & 0x3f)
-		case pOpShortPatterns:
-			var patternTexture uint8 = 0
-
-			if !state.patternCode.isSolid() {
-				texture, err := r.bits.Read8(8)
-				if err != nil {
-					return nil, err
-				}
-				patternTexture = texture >> 1
-			}
-
-			x, y, err := r.getPoint24()
+			code, err := r.read8()
 			if err != nil {
 				return nil, err
 			}
-			state.drawPattern(x, y, patternTexture)
-
-			for {
-				if peek, err := r.bits.Peek8(8); err != nil {
-					return nil, err
-				} else if peek >= 0xf0 {
-					break
-				}
-
-				if !state.patternCode.isSolid() {
-					texture, err := r.bits.Read8(8)
-					if err != nil {
-						return nil, err
-					}
-					patternTexture = texture >> 1
-				}
-
-				x, y, err = r.getPoint8(x, y)
-				if err != nil {
-					return nil, err
-				}
-				state.drawPattern(x, y, patternTexture)
-			}
-		case pOpMediumPatterns:
-			var patternTexture uint8 = 0
-
-			if !state.patternCode.isSolid() {
-				texture, err := r.bits.Read8(8)
-				if err != nil {
-					return nil, err
-				}
-				patternTexture = texture >> 1
+			pattern = PatternCode(code & 0x3f)
+			cmds = append(cmds, PicSetPattern{PicOffset: at, Code: pattern})
+		case pOpShortPatterns, pOpMediumPatterns, pOpLongPatterns:
+			var delta func(x, y int) (int, int, error)
+			switch op {
+			case pOpShortPatterns:
+				delta = r.getPoint8
+			case pOpMediumPatterns:
+				delta = r.getPoint16
 			}
 
-			x, y, err := r.getPoint24()
+			points, err := r.getPatternPoints(pattern, delta)
 			if err != nil {
 				return nil, err
 			}
-			state.drawPattern(x, y, patternTexture)
-
-			for {
-				if peek, err := r.bits.Peek8(8); err != nil {
-					return nil, err
-				} else if peek >= 0xf0 {
-					break
-				}
-
-				if !state.patternCode.isSolid() {
-					texture, err := r.bits.Read8(8)
-					if err != nil {
-						return nil, err
-					}
-					patternTexture = texture >> 1
-				}
-
-				x, y, err = r.getPoint16(x, y)
-				if err != nil {
-					return nil, err
-				}
-				state.drawPattern(x, y, patternTexture)
-			}
-		case pOpLongPatterns:
-			for {
-				var patternTexture uint8 = 0
-
-				if peek, err := r.bits.Peek8(8); err != nil {
-					return nil, err
-				} else if peek >= 0xf0 {
-					break
-				}
-
-				if !state.patternCode.isSolid() {
-					texture, err := r.bits.Read8(8)
-					if err != nil {
-						return nil, err
-					}
-					patternTexture = texture >> 1
-				}
-
-				x, y, err := r.getPoint24()
-				if err != nil {
-					return nil, err
-				}
-				state.drawPattern(x, y, patternTexture)
-			}
+			cmds = append(cmds, PicPattern{PicOffset: at, Code: pattern, Points: points})
 
 		// Extensions
 		case pOpOPX:
-			opx, err := r.bits.Read8(8)
+			opx, err := r.read8()
 			if err != nil {
 				return nil, err
 			}
 
 			switch pOpxCode(opx) {
 			case pOpxUpdatePalette:
+				var entries []PicPaletteEntry
 				for {
-					if peek, err := r.bits.Peek8(8); err != nil {
+					if done, err := r.atOpCode(); err != nil {
 						return nil, err
-					} else if peek >= 0xf0 {
+					} else if done {
 						break
 					}
 
-					code, err := r.bits.Read8(8)
+					code, err := r.read8()
 					if err != nil {
 						return nil, err
 					}
+					if int(code) >= 4*len(PicPalette{}) {
+						return nil, fmt.Errorf("invalid palette entry %d", code)
+					}
 
-					color, err := r.bits.Read8(8)
+					color, err := r.read8()
 					if err != nil {
 						return nil, err
 					}
 
-					pal, idx := code/40, code%40
-					state.palettes[pal][idx] = color
+					entries = append(entries, PicPaletteEntry{Index: code, Color: color})
 				}
+				cmds = append(cmds, PicUpdatePalette{PicOffset: at, Entries: entries})
 
-			case pOpxSetPalette:
-				i, err := r.bits.Read8(8)
+			case pOpxSetPalette, pOpxSetMonoPalette:
+				i, err := r.read8()
 				if err != nil {
 					return nil, err
 				}
-				err = binary.Read(r.bits, binary.LittleEndian, &state.palettes[i])
-				if err != nil {
+				if i >= 4 {
+					return nil, fmt.Errorf("invalid palette %d", i)
+				}
+
+				var colors PicPalette
+				if err := r.read(colors[:]); err != nil {
 					return nil, err
 				}
-			case pOpxCode(0x02):
-				// TODO this looks like a palette, but not sure what its supposed to be used for...
-				var pal struct {
-					I uint8
-					P picPalette
+
+				if opx == pOpxSetPalette {
+					cmds = append(cmds, PicSetPalette{PicOffset: at, Palette: i, Colors: colors})
+				} else {
+					cmds = append(cmds, PicSetMonoPalette{PicOffset: at, Palette: i, Colors: colors})
 				}
-				err := binary.Read(r.bits, binary.LittleEndian, &pal)
+			case pOpxSetMonoVisual:
+				code, err := r.read8()
 				if err != nil {
 					return nil, err
 				}
-				// state.palettes[pal.I] = pal.P
-			case pOpxCode(0x03), pOpxCode(0x05):
-				// TODO not sure what this byte is for...
-				if err := r.bits.Skip(8); err != nil {
+				cmds = append(cmds, PicSetMonoVisual{PicOffset: at, Color: code})
+			case pOpxDisableMonoVisual:
+				cmds = append(cmds, PicDisableMonoVisual{PicOffset: at})
+			case pOpxSetMonoPriority:
+				code, err := r.read8()
+				if err != nil {
 					return nil, err
 				}
-			case pOpxCode(0x04), pOpxCode(0x06):
-				// TODO not sure what this OP is for, but it appears to have no payload
-			case pOpxCode(0x07):
-				// TODO not sure what this is for (QfG2 uses this op-code)
-				// Vector?
-				if err := r.bits.Skip(24); err != nil {
+				cmds = append(cmds, PicSetMonoPriority{PicOffset: at, Priority: code})
+			case pOpxDisableMonoPriority:
+				cmds = append(cmds, PicDisableMonoPriority{PicOffset: at})
+			case pOpxEmbeddedCel:
+				x, y, err := r.getPoint24()
+				if err != nil {
 					return nil, err
 				}
 
-				var length uint16
-				if err := binary.Read(r.bits, binary.LittleEndian, &length); err != nil {
+				var length [2]uint8
+				if err := r.read(length[:]); err != nil {
 					return nil, err
 				}
 
-				// Payload?
-				for i := uint(0); i < uint(length); i++ {
-					if err := r.bits.Skip(8); err != nil {
-						return nil, err
-					}
+				data := make([]byte, binary.LittleEndian.Uint16(length[:]))
+				if err := r.read(data); err != nil {
+					return nil, err
 				}
 
-			case pOpxCode(0x08):
-				// TODO not sure what this is for (KQ1-sci0 remake uses this op-code)
-				for {
-					if peek, err := r.bits.Peek8(8); err != nil {
-						return nil, err
-					} else if peek >= 0xf0 {
-						break
-					}
-					if err := r.bits.Skip(8); err != nil {
-						return nil, err
-					}
+				cel, err := readEmbeddedCel(data)
+				if err != nil {
+					return nil, err
 				}
+				cmds = append(cmds, PicEmbeddedCel{
+					PicOffset: at,
+					Position:  image.Point{X: x, Y: y},
+					Cel:       cel,
+				})
+			case pOpxSetPriorityBands:
+				var bands PriorityBands
+				if err := r.read(bands[:]); err != nil {
+					return nil, err
+				}
+				cmds = append(cmds, PicSetPriorityBands{PicOffset: at, Bands: bands})
 			default:
 				return nil, fmt.Errorf("unhandled OPX 0x%02x", opx)
 			}
 
 		case pOpDone:
-			break opLoop
+			return append(cmds, PicDone{PicOffset: at}), nil
 		default:
 			return nil, fmt.Errorf("unhandled OP 0x%02x", op)
 		}
+	}
+}
+
+func newPicState(scaler screen.Scaler, debugFn DebugCallback) *PicState {
+	bounds := image.Rect(0, 0, 320, 190)
+
+	var state = PicState{
+		Pic:      scaler.NewPic(bounds),
+		drawMode: PicDrawVisual | PicDrawPriority,
+		palettes: [4]PicPalette{
+			defaultPalette,
+			defaultPalette,
+			defaultPalette,
+			defaultPalette,
+		},
+		monoPalettes: [4]PicPalette{
+			defaultPalette,
+			defaultPalette,
+			defaultPalette,
+			defaultPalette,
+		},
+		priorityBands: DefaultPriorityBands,
+		debugFn:       debugFn,
+	}
+
+	state.Visual().Clear(0xFF)
+	state.Control().Clear(0x0)
+	state.Priority().Clear(0x0)
+
+	return &state
+}
+
+func readPic(payload []byte, opts PicOptions) (*PicState, error) {
+	cmds, err := DecodePicCommands(payload)
+	if err != nil {
+		return nil, err
+	}
 
+	state := newPicState(opts.Scaler, opts.DebugFn)
+	state.mode = opts.Mode
+	for _, cmd := range cmds {
+		cmd.apply(state)
 	}
 
-	return state.Pic, nil
+	return state, nil
 }
@@ -0,0 +1,230 @@
+package resource
+
+import "image"
+
+// PicCommand is a single decoded drawing command of a picture resource.
+type PicCommand interface {
+	// Offset is the position of the command's op-code within the resource.
+	Offset() int
+
+	apply(s *PicState)
+}
+
+// PicOffset is the position of a command's op-code within a picture resource.
+type PicOffset int
+
+func (o PicOffset) Offset() int { return int(o) }
+
+// PicSetVisual enables drawing to the visual layer, with the color at
+// index Color of the picture's palettes.
+type PicSetVisual struct {
+	PicOffset
+	Color uint8
+}
+
+func (c PicSetVisual) apply(s *PicState) {
+	s.colorCode = colorCode(c.Color)
+	s.drawMode.set(PicDrawVisual, true)
+}
+
+// PicDisableVisual disables drawing to the visual layer.
+type PicDisableVisual struct{ PicOffset }
+
+func (c PicDisableVisual) apply(s *PicState) {
+	s.drawMode.set(PicDrawVisual, false)
+}
+
+// PicSetPriority enables drawing to the priority layer.
+type PicSetPriority struct {
+	PicOffset
+	Priority uint8
+}
+
+func (c PicSetPriority) apply(s *PicState) {
+	s.priorityCode = nybbleCode(c.Priority)
+	s.drawMode.set(PicDrawPriority, true)
+}
+
+// PicDisablePriority disables drawing to the priority layer.
+type PicDisablePriority struct{ PicOffset }
+
+func (c PicDisablePriority) apply(s *PicState) {
+	s.drawMode.set(PicDrawPriority, false)
+}
+
+// PicSetControl enables drawing to the control layer.
+type PicSetControl struct {
+	PicOffset
+	Control uint8
+}
+
+func (c PicSetControl) apply(s *PicState) {
+	s.controlCode = nybbleCode(c.Control)
+	s.drawMode.set(PicDrawControl, true)
+}
+
+// PicDisableControl disables drawing to the control layer.
+type PicDisableControl struct{ PicOffset }
+
+func (c PicDisableControl) apply(s *PicState) {
+	s.drawMode.set(PicDrawControl, false)
+}
+
+// PicLine draws connected line segments between each of its Points.
+type PicLine struct {
+	PicOffset
+	Points []image.Point
+}
+
+func (c PicLine) apply(s *PicState) {
+	for i := 1; i < len(c.Points); i++ {
+		p1, p2 := c.Points[i-1], c.Points[i]
+		s.line(p1.X, p1.Y, p2.X, p2.Y)
+	}
+}
+
+// PicFill flood-fills from each of its Points.
+type PicFill struct {
+	PicOffset
+	Points []image.Point
+}
+
+func (c PicFill) apply(s *PicState) {
+	for _, p := range c.Points {
+		s.fill(p.X, p.Y)
+	}
+}
+
+// PicSetPattern changes the brush used by subsequent patterns.
+type PicSetPattern struct {
+	PicOffset
+	Code PatternCode
+}
+
+func (c PicSetPattern) apply(s *PicState) {
+	s.patternCode = c.Code
+}
+
+// PicPatternPoint is the position of a single pattern, and the texture it
+// is drawn with when the brush is textured.
+type PicPatternPoint struct {
+	image.Point
+	Texture uint8
+}
+
+// PicPattern draws a pattern at each of its Points, using the brush Code.
+type PicPattern struct {
+	PicOffset
+	Code   PatternCode
+	Points []PicPatternPoint
+}
+
+func (c PicPattern) apply(s *PicState) {
+	s.patternCode = c.Code
+	for _, p := range c.Points {
+		s.drawPattern(p.X, p.Y, p.Texture)
+	}
+}
+
+// PicPaletteEntry replaces the color at Index of the picture's palettes.
+type PicPaletteEntry struct {
+	Index uint8
+	Color uint8
+}
+
+// PicUpdatePalette replaces individual entries of the picture's palettes.
+type PicUpdatePalette struct {
+	PicOffset
+	Entries []PicPaletteEntry
+}
+
+func (c PicUpdatePalette) apply(s *PicState) {
+	for _, e := range c.Entries {
+		pal, idx := e.Index/40, e.Index%40
+		s.palettes[pal][idx] = e.Color
+	}
+}
+
+// PicSetPalette replaces an entire palette.
+type PicSetPalette struct {
+	PicOffset
+	Palette uint8
+	Colors  PicPalette
+}
+
+func (c PicSetPalette) apply(s *PicState) {
+	s.palettes[c.Palette] = c.Colors
+}
+
+// PicSetMonoPalette replaces an entire monochrome palette.
+type PicSetMonoPalette struct {
+	PicOffset
+	Palette uint8
+	Colors  PicPalette
+}
+
+func (c PicSetMonoPalette) apply(s *PicState) {
+	s.monoPalettes[c.Palette] = c.Colors
+}
+
+// PicSetMonoVisual sets the visual color used by monochrome displays.
+type PicSetMonoVisual struct {
+	PicOffset
+	Color uint8
+}
+
+func (c PicSetMonoVisual) apply(s *PicState) {
+	s.monoColorCode = colorCode(c.Color)
+	s.monoDrawMode.set(PicDrawVisual, true)
+}
+
+// PicDisableMonoVisual disables the visual color used by monochrome displays.
+type PicDisableMonoVisual struct{ PicOffset }
+
+func (c PicDisableMonoVisual) apply(s *PicState) {
+	s.monoDrawMode.set(PicDrawVisual, false)
+}
+
+// PicSetMonoPriority sets the priority used by monochrome displays.
+type PicSetMonoPriority struct {
+	PicOffset
+	Priority uint8
+}
+
+func (c PicSetMonoPriority) apply(s *PicState) {
+	s.monoPriorityCode = nybbleCode(c.Priority)
+	s.monoDrawMode.set(PicDrawPriority, true)
+}
+
+// PicDisableMonoPriority disables the priority used by monochrome displays.
+type PicDisableMonoPriority struct{ PicOffset }
+
+func (c PicDisableMonoPriority) apply(s *PicState) {
+	s.monoDrawMode.set(PicDrawPriority, false)
+}
+
+// PicEmbeddedCel draws a bitmap with its top-left corner at Position.
+type PicEmbeddedCel struct {
+	PicOffset
+	Position image.Point
+	Cel      Sprite
+}
+
+func (c PicEmbeddedCel) apply(s *PicState) {
+	s.drawCel(c.Position.X, c.Position.Y, c.Cel)
+}
+
+// PicSetPriorityBands replaces the priority bands of the picture.
+type PicSetPriorityBands struct {
+	PicOffset
+	Bands PriorityBands
+}
+
+func (c PicSetPriorityBands) apply(s *PicState) {
+	s.priorityBands = c.Bands
+}
+
+// PicDone marks the end of the picture.
+type PicDone struct{ PicOffset }
+
+func (c PicDone) apply(*PicState) {}
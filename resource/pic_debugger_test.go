@@ -0,0 +1,130 @@
+package resource
+
+import (
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestPicDebugger(t *testing.T) *PicDebugger {
+	b, err := EncodePicCommands([]PicCommand{
+		PicSetVisual{Color: 1},
+		PicLine{Points: []image.Point{{0, 50}, {319, 50}}},
+		PicSetVisual{Color: 2},
+		PicFill{Points: []image.Point{{10, 100}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewPicDebugger(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestPicDebugger(t *testing.T) {
+	d := newTestPicDebugger(t)
+	if len(d.Commands) != 5 {
+		t.Fatalf("got %d commands, expected 5", len(d.Commands))
+	}
+
+	first, ok := d.Step()
+	if !ok || first.Index != 0 || first.Draws() || first.ColorCode != 1 {
+		t.Fatalf("got step %+v, %v", first, ok)
+	}
+	line, _ := d.Step()
+	if !line.Draws() {
+		t.Error("a line should draw")
+	}
+	if c := line.Visual.(*image.Paletted).ColorIndexAt(10, 50); c != 1 {
+		t.Errorf("got color %d, expected 1", c)
+	}
+	if c := first.Visual.(*image.Paletted).ColorIndexAt(10, 50); c != 15 {
+		t.Errorf("an earlier step was modified: got color %d, expected 15", c)
+	}
+
+	color, _ := d.Step()
+	if color.Visual != line.Visual || color.Control != line.Control {
+		t.Error("a step that does not draw should share the layers of the step before it")
+	}
+	if color.ColorCode != 2 {
+		t.Errorf("got color code %d, expected 2", color.ColorCode)
+	}
+
+	if _, ok := d.StopAfter(1); ok {
+		t.Error("stopped after a command that was already executed")
+	}
+	if _, ok := d.StopAfter(len(d.Commands)); ok {
+		t.Error("stopped after a command that does not exist")
+	}
+
+	fill, ok := d.StopAfter(3)
+	if !ok || fill.Index != 3 {
+		t.Fatalf("got step %+v, %v", fill, ok)
+	}
+	if fill.Visual == color.Visual {
+		t.Error("a fill should copy the layers")
+	}
+	if c := fill.Layer(PicVisualLayer).(*image.Paletted).ColorIndexAt(10, 100); c != 2 {
+		t.Errorf("got color %d, expected 2", c)
+	}
+
+	if steps := d.Steps(); len(steps) != 1 || !d.Done() {
+		t.Errorf("got %d remaining steps, expected 1", len(steps))
+	}
+	if _, ok := d.Step(); ok {
+		t.Error("stepped past the last command")
+	}
+}
+
+func TestPicStepsGIF(t *testing.T) {
+	steps := newTestPicDebugger(t).Steps()
+	anim := PicStepsGIF(steps, PicVisualLayer, 5)
+	if len(anim.Image) != len(steps) || len(anim.Delay) != len(steps) {
+		t.Fatalf("got %d frames and %d delays, expected %d", len(anim.Image), len(anim.Delay), len(steps))
+	}
+	if anim.Delay[0] != 5 {
+		t.Errorf("got delay %d, expected 5", anim.Delay[0])
+	}
+	if c := anim.Image[3].ColorIndexAt(10, 100); c != 2 {
+		t.Errorf("got color %d, expected 2", c)
+	}
+}
+
+func TestWritePicStepsPNG(t *testing.T) {
+	dir, err := ioutil.TempDir("", "steps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	steps := newTestPicDebugger(t).Steps()
+	if err := WritePicStepsPNG(dir, steps[1:], PicControlLayer); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(steps)-1 || files[0].Name() != "0001.png" {
+		t.Fatalf("got %d files, starting with %s", len(files), files[0].Name())
+	}
+
+	f, err := os.Open(filepath.Join(dir, "0001.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != steps[1].Control.Bounds() {
+		t.Errorf("got bounds %v, expected %v", img.Bounds(), steps[1].Control.Bounds())
+	}
+}
@@ -0,0 +1,388 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+)
+
+// EncodePicCommands packs a list of commands into the op-code stream of a
+// picture resource. Lines and patterns are written with whichever mix of the
+// short, medium and long encodings produces the fewest bytes. Encoding stops
+// at the first PicDone, and one is appended if the list does not have one.
+func EncodePicCommands(cmds []PicCommand) ([]byte, error) {
+	var (
+		w       picWriter
+		pattern PatternCode
+	)
+
+	for _, cmd := range cmds {
+		var err error
+		switch c := cmd.(type) {
+		case PicSetVisual:
+			w.op(pOpSetVisual, c.Color)
+		case PicDisableVisual:
+			w.op(pOpDisableVisual)
+		case PicSetPriority:
+			w.op(pOpSetPriority, c.Priority)
+		case PicDisablePriority:
+			w.op(pOpDisablePriority)
+		case PicSetControl:
+			w.op(pOpSetControl, c.Control)
+		case PicDisableControl:
+			w.op(pOpDisableControl)
+		case PicLine:
+			err = w.lines(c.Points)
+		case PicFill:
+			w.op(pOpFills)
+			for _, p := range c.Points {
+				if err = w.putPoint24(p); err != nil {
+					break
+				}
+			}
+		case PicSetPattern:
+			pattern = c.Code
+			w.op(pOpSetPattern, uint8(pattern))
+		case PicPattern:
+			if c.Code != pattern {
+				pattern = c.Code
+				w.op(pOpSetPattern, uint8(pattern))
+			}
+			err = w.patterns(c.Points, !pattern.IsSolid())
+		case PicUpdatePalette:
+			w.opx(pOpxUpdatePalette)
+			for _, e := range c.Entries {
+				if int(e.Index) >= 4*len(PicPalette{}) {
+					err = fmt.Errorf("invalid palette entry %d", e.Index)
+					break
+				}
+				w.WriteByte(e.Index)
+				w.WriteByte(e.Color)
+			}
+		case PicSetPalette:
+			err = w.palette(pOpxSetPalette, c.Palette, c.Colors)
+		case PicSetMonoPalette:
+			err = w.palette(pOpxSetMonoPalette, c.Palette, c.Colors)
+		case PicSetMonoVisual:
+			w.opx(pOpxSetMonoVisual, c.Color)
+		case PicDisableMonoVisual:
+			w.opx(pOpxDisableMonoVisual)
+		case PicSetMonoPriority:
+			w.opx(pOpxSetMonoPriority, c.Priority)
+		case PicDisableMonoPriority:
+			w.opx(pOpxDisableMonoPriority)
+		case PicEmbeddedCel:
+			err = w.embeddedCel(c.Position, c.Cel)
+		case PicSetPriorityBands:
+			w.opx(pOpxSetPriorityBands, c.Bands[:]...)
+		case PicDone:
+			w.op(pOpDone)
+			return w.Bytes(), nil
+		default:
+			err = fmt.Errorf("unhandled command %T", cmd)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("command at %d: %w", cmd.Offset(), err)
+		}
+	}
+
+	w.op(pOpDone)
+	return w.Bytes(), nil
+}
+
+type picWriter struct {
+	bytes.Buffer
+}
+
+func (w *picWriter) op(op pOpCode, args ...uint8) {
+	w.WriteByte(uint8(op))
+	w.Write(args)
+}
+
+func (w *picWriter) opx(opx pOpxCode, args ...uint8) {
+	w.op(pOpOPX, uint8(opx))
+	w.Write(args)
+}
+
+func (w *picWriter) palette(opx pOpxCode, i uint8, colors PicPalette) error {
+	if i >= 4 {
+		return fmt.Errorf("invalid palette %d", i)
+	}
+	w.opx(opx, i)
+	w.Write(colors[:])
+	return nil
+}
+
+func (w *picWriter) embeddedCel(p image.Point, cel Sprite) error {
+	if len(cel.Pixels) != int(cel.Width)*int(cel.Height) {
+		return fmt.Errorf("cel has %d pixels, expected %dx%d", len(cel.Pixels), cel.Width, cel.Height)
+	}
+
+	var data bytes.Buffer
+	if err := binary.Write(&data, binary.LittleEndian, cel.SpriteHeader); err != nil {
+		return err
+	}
+	data.WriteByte(0)
+	if err := writeSpritePixels(&data, cel.Pixels, int(cel.Width)); err != nil {
+		return err
+	}
+	if data.Len() > 0xFFFF {
+		return fmt.Errorf("cel is too large to embed")
+	}
+
+	w.opx(pOpxEmbeddedCel)
+	if err := w.putPoint24(p); err != nil {
+		return err
+	}
+	var length [2]uint8
+	binary.LittleEndian.PutUint16(length[:], uint16(data.Len()))
+	w.Write(length[:])
+	w.Write(data.Bytes())
+	return nil
+}
+
+// putPoint24 is the inverse of picReader.getPoint24.
+func (w *picWriter) putPoint24(p image.Point) error {
+	if p.X < 0 || p.Y < 0 || p.X >= 0xF00 || p.Y >= 0x1000 {
+		return fmt.Errorf("point %v out of range", p)
+	}
+	w.WriteByte(uint8((p.X>>8)<<4 | p.Y>>8))
+	w.WriteByte(uint8(p.X))
+	w.WriteByte(uint8(p.Y))
+	return nil
+}
+
+// putPoint16 is the inverse of picReader.getPoint16.
+func (w *picWriter) putPoint16(d image.Point) {
+	if d.Y < 0 {
+		w.WriteByte(0x80 | uint8(-d.Y))
+	} else {
+		w.WriteByte(uint8(d.Y))
+	}
+	w.WriteByte(uint8(int8(d.X)))
+}
+
+// putPoint8 is the inverse of picReader.getPoint8.
+func (w *picWriter) putPoint8(d image.Point) {
+	nybble := func(v int) uint8 {
+		if v < 0 {
+			return 0x8 | uint8(-v)
+		}
+		return uint8(v)
+	}
+	w.WriteByte(nybble(d.X)<<4 | nybble(d.Y))
+}
+
+// pointEncoding is one of the three ways that a list of points can be packed.
+type pointEncoding int
+
+const (
+	shortPoints pointEncoding = iota
+	mediumPoints
+	longPoints
+)
+
+func (e pointEncoding) size() int {
+	return int(e) + 1
+}
+
+// fits reports whether the delta d can be written with the encoding. When the
+// first byte of the point is peeked by the reader, it must not be mistaken for
+// an op-code, which shrinks the range of negative deltas.
+func (e pointEncoding) fits(d image.Point, peeked bool) bool {
+	switch e {
+	case shortPoints:
+		minX := -7
+		if peeked {
+			minX = -6
+		}
+		return d.X >= minX && d.X <= 7 && d.Y >= -7 && d.Y <= 7
+	case mediumPoints:
+		minY := -0x7F
+		if peeked {
+			minY = -0x6F
+		}
+		return d.X >= -0x80 && d.X <= 0x7F && d.Y >= minY && d.Y <= 0x7F
+	default:
+		return true
+	}
+}
+
+func (w *picWriter) putPoint(e pointEncoding, from, to image.Point) error {
+	switch e {
+	case shortPoints:
+		w.putPoint8(to.Sub(from))
+	case mediumPoints:
+		w.putPoint16(to.Sub(from))
+	default:
+		return w.putPoint24(to)
+	}
+	return nil
+}
+
+var lineOps = [...]pOpCode{
+	shortPoints:  pOpShortLines,
+	mediumPoints: pOpMediumLines,
+	longPoints:   pOpLongLines,
+}
+
+var patternOps = [...]pOpCode{
+	shortPoints:  pOpShortPatterns,
+	mediumPoints: pOpMediumPatterns,
+	longPoints:   pOpLongPatterns,
+}
+
+// span is a run of points that are written with a single op-code.
+type span struct {
+	start, end int
+	pointEncoding
+}
+
+// lines writes a polyline, splitting it into as many op-codes as necessary
+// to give the smallest encoding. Consecutive op-codes share a vertex.
+func (w *picWriter) lines(points []image.Point) error {
+	switch len(points) {
+	case 0:
+		return nil
+	case 1:
+		w.op(pOpShortLines)
+		return w.putPoint24(points[0])
+	}
+
+	// cost[i] is the cheapest encoding of the first i+1 points
+	n := len(points)
+	cost := make([]int, n)
+	spans := make([]span, n)
+	var starts [3]spanStart
+	for end := 1; end < n; end++ {
+		d := points[end].Sub(points[end-1])
+		for e := range starts {
+			size := pointEncoding(e).size()
+			starts[e].add(end-1, cost[end-1]-(end-1)*size)
+			if !pointEncoding(e).fits(d, true) {
+				starts[e] = spanStart{}
+			}
+		}
+		cost[end], spans[end] = cheapestSpan(starts, end, func(e pointEncoding) int {
+			return 4 + end*e.size()
+		})
+	}
+
+	for _, s := range reverseSpans(spans, n) {
+		w.op(lineOps[s.pointEncoding])
+		if err := w.putPoint24(points[s.start]); err != nil {
+			return err
+		}
+		for i := s.start + 1; i <= s.end; i++ {
+			if err := w.putPoint(s.pointEncoding, points[i-1], points[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// patterns writes a list of patterns, splitting it into as many op-codes as
+// necessary to give the smallest encoding.
+func (w *picWriter) patterns(points []PicPatternPoint, textured bool) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	tex := 0
+	if textured {
+		tex = 1
+	}
+
+	// cost[i] is the cheapest encoding of the first i points
+	n := len(points)
+	cost := make([]int, n+1)
+	spans := make([]span, n+1)
+	var starts [3]spanStart
+	for end := 1; end <= n; end++ {
+		for e := range starts {
+			if end > 1 {
+				d := points[end-1].Sub(points[end-2].Point)
+				if !pointEncoding(e).fits(d, !textured) {
+					starts[e] = spanStart{}
+				}
+			}
+			size := pointEncoding(e).size() + tex
+			starts[e].add(end-1, cost[end-1]-(end-1)*size)
+		}
+		cost[end], spans[end] = cheapestSpan(starts, end, func(e pointEncoding) int {
+			return 1 + 3 + tex + (end-1)*(e.size()+tex)
+		})
+	}
+
+	for _, s := range reverseSpans(spans, n+1) {
+		w.op(patternOps[s.pointEncoding])
+		for i := s.start; i < s.end; i++ {
+			p := points[i]
+			if textured {
+				if p.Texture >= 0x78 {
+					return fmt.Errorf("invalid texture %d", p.Texture)
+				}
+				w.WriteByte(p.Texture << 1)
+			}
+
+			var err error
+			if i == s.start {
+				err = w.putPoint24(p.Point)
+			} else {
+				err = w.putPoint(s.pointEncoding, points[i-1].Point, p.Point)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// spanStart is the best point to start a span of one encoding from. A span
+// that starts at start, and ends at end, costs value plus the part of its
+// cost that only depends on end, so one start serves every end.
+type spanStart struct {
+	start, value int
+	ok           bool
+}
+
+// add offers start as a new start. Later starts win ties, as they give
+// fewer op-codes at the end of the points.
+func (s *spanStart) add(start, value int) {
+	if !s.ok || value <= s.value {
+		*s = spanStart{start: start, value: value, ok: true}
+	}
+}
+
+// cheapestSpan picks the cheapest span that ends at end. Ties go to the
+// later start, then to the shorter encoding.
+func cheapestSpan(starts [3]spanStart, end int, rest func(pointEncoding) int) (int, span) {
+	cost, best := -1, span{}
+	for e, s := range starts {
+		if !s.ok {
+			continue
+		}
+		c := s.value + rest(pointEncoding(e))
+		if cost < 0 || c < cost || c == cost && s.start > best.start {
+			cost, best = c, span{s.start, end, pointEncoding(e)}
+		}
+	}
+	return cost, best
+}
+
+// reverseSpans walks the chosen spans back from the last point, and
+// returns them in order.
+func reverseSpans(spans []span, n int) []span {
+	var result []span
+	for end := n - 1; end > 0; end = spans[end].start {
+		result = append(result, spans[end])
+	}
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
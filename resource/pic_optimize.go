@@ -0,0 +1,23 @@
+package resource
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/32bitkid/sci/screen"
+)
+
+// OptimizePicDitherer draws picture resources with a screen.PairScaler, and
+// finds the colors that best replace their dithering with
+// screen.OptimizeDitherer. The palette may be nil, to use the EGA palette.
+func OptimizePicDitherer(pal color.Palette, pics ...[]byte) (*screen.Ditherer, error) {
+	images := make([]*image.Paletted, 0, len(pics))
+	for _, b := range pics {
+		pic, err := NewPic(b, PicOptions{Scaler: screen.PairScaler{}})
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, pic.Visual().Image().(*image.Paletted))
+	}
+	return screen.OptimizeDitherer(pal, images...), nil
+}
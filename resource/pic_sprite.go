@@ -0,0 +1,72 @@
+package resource
+
+import (
+	"image"
+
+	"github.com/32bitkid/sci/screen"
+)
+
+// layerAt returns the value of a priority or control layer at x, y. Points
+// outside of the layer are 0.
+func layerAt(b screen.Buffer, x, y int) uint8 {
+	img, ok := b.Image().(interface {
+		image.Image
+		ColorIndexAt(x, y int) uint8
+	})
+	if !ok || !(image.Point{X: x, Y: y}).In(img.Bounds()) {
+		return 0
+	}
+	return img.ColorIndexAt(x, y) & 0xF
+}
+
+// DrawSprite draws a cel onto the visual layer with its origin at x, y, see
+// Sprite.Rect. Like the interpreter, each pixel of the cel is hidden where
+// the priority layer is higher than priority, and the priority layer is
+// raised to priority where the cel is drawn. Pixels of the key color are
+// transparent, and mirrored cels are drawn mirrored.
+func (s *PicState) DrawSprite(cel Sprite, x, y int, priority uint8) {
+	rect := cel.Rect(x, y)
+	priority &= 0xF
+	for cy := 0; cy < int(cel.Height); cy++ {
+		for cx := 0; cx < int(cel.Width); cx++ {
+			c := cel.At(cx, cy)
+			if c == cel.KeyColor {
+				continue
+			}
+			px, py := rect.Min.X+cx, rect.Min.Y+cy
+			if layerAt(s.Priority(), px, py) > priority {
+				continue
+			}
+			screen.Plot(s.Visual(), px, py, c<<4|c)
+			screen.Plot(s.Priority(), px, py, priority)
+		}
+	}
+}
+
+// DrawActor draws a cel at the priority of the band that contains y, the way
+// the interpreter draws actors that do not have a fixed priority.
+func (s *PicState) DrawActor(cel Sprite, x, y int) {
+	s.DrawSprite(cel, x, y, s.priorityBands.Priority(y))
+}
+
+// OnControl returns the set of control colors within r, as a bit mask with
+// bit n set when color n is present, like the interpreter's OnControl.
+func (s *PicState) OnControl(r image.Rectangle) uint16 {
+	var mask uint16
+	r = r.Intersect(s.Control().Image().Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			mask |= 1 << layerAt(s.Control(), x, y)
+		}
+	}
+	return mask
+}
+
+// Base returns the line that a cel with its origin at x, y stands on. It is
+// the bottom row of the cel, and is the area that actors test against the
+// control layer when they move.
+func (s Sprite) Base(x, y int) image.Rectangle {
+	r := s.Rect(x, y)
+	r.Min.Y = r.Max.Y - 1
+	return r
+}
@@ -0,0 +1,54 @@
+package resource
+
+import (
+	"image"
+	"testing"
+
+	"github.com/32bitkid/sci/screen"
+)
+
+func TestDrawSprite(t *testing.T) {
+	s := newPicState(screen.Scaler1x1{Ditherer: screen.DefaultDitherers.EGA}, nil)
+	// a wall at priority 10 covers x=72..87
+	s.Priority().Pattern(79, 100, 7, true, true, 0, 10)
+	s.Control().Line(0, 120, 319, 120, 4)
+
+	cel := Sprite{
+		SpriteHeader: SpriteHeader{Width: 4, Height: 2, KeyColor: 0xF},
+		Mirrored:     true,
+		Pixels:       []uint8{1, 2, 3, 0xF, 4, 4, 4, 4},
+	}
+
+	// the cel covers x=86..89, y=100..101, and straddles the edge of the wall
+	s.DrawSprite(cel, 88, 101, 5)
+	visual := s.Visual().Image().(*image.Paletted)
+	priority := s.Priority().Image().(*image.Paletted)
+
+	tests := []struct {
+		x, y     int
+		color    uint8
+		priority uint8
+	}{
+		{86, 100, 0xF, 10}, // key color, mirrored to the left
+		{87, 100, 0xF, 10}, // behind the wall
+		{88, 100, 2, 5},
+		{89, 100, 1, 5},
+		{87, 101, 0xF, 10},
+		{88, 101, 4, 5},
+	}
+	for _, tt := range tests {
+		if c := visual.ColorIndexAt(tt.x, tt.y); c != tt.color {
+			t.Errorf("%d,%d: got color %d, expected %d", tt.x, tt.y, c, tt.color)
+		}
+		if p := priority.ColorIndexAt(tt.x, tt.y); p != tt.priority {
+			t.Errorf("%d,%d: got priority %d, expected %d", tt.x, tt.y, p, tt.priority)
+		}
+	}
+
+	if mask := s.OnControl(cel.Base(150, 120)); mask != 1<<4 {
+		t.Errorf("got control mask %016b", mask)
+	}
+	if mask := s.OnControl(cel.Base(150, 119)); mask != 1<<0 {
+		t.Errorf("got control mask %016b", mask)
+	}
+}
@@ -0,0 +1,18 @@
+package resource
+
+import (
+	"io"
+
+	"github.com/32bitkid/sci/screen"
+)
+
+// WritePicSVG draws a picture resource with a screen.SVGScaler, and writes
+// its visual layer as an SVG document. The ditherer may be nil, to use the
+// EGA palette.
+func WritePicSVG(w io.Writer, b []byte, d *screen.Ditherer) error {
+	pic, err := NewPic(b, PicOptions{Scaler: screen.SVGScaler{Ditherer: d}})
+	if err != nil {
+		return err
+	}
+	return screen.WriteSVG(w, pic)
+}
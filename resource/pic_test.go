@@ -0,0 +1,412 @@
+package resource
+
+import (
+	"bytes"
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/32bitkid/sci/screen"
+)
+
+func TestEncodeLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		points   []image.Point
+		expected []byte
+	}{
+		{
+			name:     "short",
+			points:   []image.Point{{10, 10}, {12, 13}, {11, 9}},
+			expected: []byte{0xf7, 0x00, 0x0a, 0x0a, 0x23, 0x9c, 0xff},
+		},
+		{
+			name:     "medium",
+			points:   []image.Point{{10, 10}, {100, 20}},
+			expected: []byte{0xf5, 0x00, 0x0a, 0x0a, 0x0a, 0x5a, 0xff},
+		},
+		{
+			name:     "long",
+			points:   []image.Point{{10, 10}, {300, 180}},
+			expected: []byte{0xf6, 0x00, 0x0a, 0x0a, 0x10, 0x2c, 0xb4, 0xff},
+		},
+		{
+			name:     "short delta that would look like an op-code",
+			points:   []image.Point{{10, 10}, {3, 10}},
+			expected: []byte{0xf5, 0x00, 0x0a, 0x0a, 0x00, 0xf9, 0xff},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := EncodePicCommands([]PicCommand{PicLine{Points: tt.points}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(actual, tt.expected) {
+				t.Errorf("got % x, expected % x", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEncodePicCommandsRoundTrip(t *testing.T) {
+	var zigzag []image.Point
+	for i := 0; i < 40; i++ {
+		zigzag = append(zigzag, image.Point{X: 8 * i, Y: 20 + (i%2)*(i*4)})
+	}
+
+	cmds := []PicCommand{
+		PicSetPriorityBands{Bands: PriorityBands{50, 60, 70, 80, 90, 100, 110, 120, 130, 140, 150, 160, 170, 180}},
+		PicUpdatePalette{Entries: []PicPaletteEntry{{Index: 41, Color: 0x4c}}},
+		PicSetVisual{Color: 41},
+		PicSetPriority{Priority: 5},
+		PicLine{Points: zigzag},
+		PicLine{Points: []image.Point{{0, 0}, {319, 0}, {319, 189}, {0, 189}, {0, 0}}},
+		PicSetVisual{Color: 2},
+		PicDisablePriority{},
+		PicFill{Points: []image.Point{{100, 100}}},
+		PicSetControl{Control: 4},
+		PicPattern{Code: 0x23, Points: []PicPatternPoint{
+			{Point: image.Point{X: 40, Y: 150}, Texture: 3},
+			{Point: image.Point{X: 44, Y: 152}, Texture: 9},
+			{Point: image.Point{X: 200, Y: 150}, Texture: 100},
+		}},
+		PicDisableControl{},
+		PicSetMonoVisual{Color: 15},
+		PicEmbeddedCel{Position: image.Point{X: 150, Y: 60}, Cel: Sprite{
+			SpriteHeader: SpriteHeader{Width: 3, Height: 2, KeyColor: 0xf},
+			Pixels:       []uint8{1, 1, 0xf, 4, 4, 4},
+		}},
+		PicDone{},
+	}
+
+	encoded, err := EncodePicCommands(cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodePicCommands(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reencoded, err := EncodePicCommands(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, reencoded) {
+		t.Errorf("re-encoded picture differs:\n% x\n% x", encoded, reencoded)
+	}
+
+	scaler := screen.Scaler1x1{Ditherer: screen.DefaultDitherers.EGA}
+	expected := newPicState(scaler, nil)
+	for _, cmd := range cmds {
+		cmd.apply(expected)
+	}
+
+	actual, err := NewPicState(encoded, PicOptions{Scaler: scaler})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layers := map[string][2]screen.Buffer{
+		"visual":   {expected.Visual(), actual.Visual()},
+		"priority": {expected.Priority(), actual.Priority()},
+		"control":  {expected.Control(), actual.Control()},
+	}
+	for name, l := range layers {
+		e, a := l[0].Image().(*image.Paletted), l[1].Image().(*image.Paletted)
+		if !bytes.Equal(e.Pix, a.Pix) {
+			t.Errorf("%s layer differs", name)
+		}
+	}
+
+	if actual.PriorityBands() != expected.PriorityBands() {
+		t.Errorf("got priority bands %v, expected %v", actual.PriorityBands(), expected.PriorityBands())
+	}
+}
+
+func TestPicModes(t *testing.T) {
+	mono := defaultPalette
+	mono[1] = 0xF0
+	mono[2] = 0x00
+
+	cmds := []PicCommand{
+		PicSetMonoPalette{Palette: 0, Colors: mono},
+		PicSetVisual{Color: 1},
+		PicLine{Points: []image.Point{{0, 10}, {319, 10}}},
+		PicSetMonoVisual{Color: 2},
+		PicLine{Points: []image.Point{{0, 20}, {319, 20}}},
+		PicDone{},
+	}
+	b, err := EncodePicCommands(cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a driver table that tells the pairs of EGA colors apart
+	var table screen.DriverTable
+	for i := range table {
+		table[i] = [2]uint8{uint8(i) >> 7, uint8(i) & 1}
+	}
+	driver := &screen.Scaler1x1{Ditherer: screen.NewDriverDitherer(screen.DefaultPalettes.Mono, table)}
+
+	tests := []struct {
+		mode     PicMode
+		scaler   screen.Scaler
+		expected [2][2]uint8 // the colors of both lines, at even and odd x
+	}{
+		{PicModeEGA, nil, [2][2]uint8{{1, 1}, {1, 1}}},
+		{PicModeCGA, driver, [2][2]uint8{{table[0x11][0], table[0x11][1]}, {table[0x11][0], table[0x11][1]}}},
+		{PicModeMono, driver, [2][2]uint8{{table[0xF0][0], table[0xF0][1]}, {table[0x00][0], table[0x00][1]}}},
+	}
+	for _, tt := range tests {
+		pic, err := NewPic(b, PicOptions{Mode: tt.mode, Scaler: tt.scaler})
+		if err != nil {
+			t.Fatal(err)
+		}
+		img := pic.Visual().Image().(*image.Paletted)
+		for i, y := range []int{10, 20} {
+			actual := [2]uint8{img.ColorIndexAt(0, y), img.ColorIndexAt(1, y)}
+			if actual != tt.expected[i] {
+				t.Errorf("mode %d, line %d: got %v, expected %v", tt.mode, i, actual, tt.expected[i])
+			}
+		}
+	}
+
+	for _, mode := range []PicMode{PicModeCGA, PicModeMono} {
+		if _, err := NewPic(b, PicOptions{Mode: mode}); err == nil {
+			t.Errorf("mode %d: expected an error without a Scaler", mode)
+		}
+	}
+}
+
+func TestResolvePicOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []PicOptions
+		expected PicMode
+	}{
+		{"none", nil, PicModeEGA},
+		{"unset", []PicOptions{{}}, PicModeEGA},
+		{"cga", []PicOptions{{Mode: PicModeCGA}, {}}, PicModeCGA},
+		{"ega after cga", []PicOptions{{Mode: PicModeCGA}, {Mode: PicModeEGA}}, PicModeEGA},
+	}
+	for _, tt := range tests {
+		opts, _ := resolvePicOptions(tt.options)
+		if mode := opts.Mode; mode != tt.expected {
+			t.Errorf("%s: got mode %d, expected %d", tt.name, mode, tt.expected)
+		}
+	}
+
+	opts, err := resolvePicOptions([]PicOptions{{Mode: PicModeCGA}, {Mode: PicModeEGA}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := opts.Scaler.(*screen.Scaler1x1); !ok || s.Ditherer != screen.DefaultDitherers.EGA {
+		t.Errorf("got scaler %#v, expected the EGA ditherer", opts.Scaler)
+	}
+}
+
+func TestDecodePicExtensions(t *testing.T) {
+	mono := defaultPalette
+	mono[5] = 0xF0
+	bands := PriorityBands{50, 60, 70, 80, 90, 100, 110, 120, 130, 140, 150, 160, 170, 180}
+
+	var b []byte
+	// OPX 0x02: mono palette 1
+	b = append(b, 0xFE, 0x02, 0x01)
+	b = append(b, mono[:]...)
+	// OPX 0x03 and 0x05: mono visual 5, mono priority 7
+	b = append(b, 0xFE, 0x03, 0x05, 0xFE, 0x05, 0x07)
+	// OPX 0x07: a 2x2 cel at 266,20, with key color 15
+	b = append(b, 0xFE, 0x07, 0x10, 0x0A, 0x14, 0x0B, 0x00,
+		0x02, 0x00, 0x02, 0x00, 0x00, 0x00, 0x0F, 0x00,
+		0x21, 0x12, 0x1F)
+	// OPX 0x08: priority bands
+	b = append(b, 0xFE, 0x08)
+	b = append(b, bands[:]...)
+	b = append(b, 0xFF)
+
+	cmds, err := DecodePicCommands(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 6 {
+		t.Fatalf("got %d commands, expected 6", len(cmds))
+	}
+	if c, ok := cmds[0].(PicSetMonoPalette); !ok || c.Palette != 1 || c.Colors != mono {
+		t.Errorf("got %+v", cmds[0])
+	}
+	if c, ok := cmds[3].(PicEmbeddedCel); !ok || c.Position != (image.Point{X: 266, Y: 20}) || c.Cel.Width != 2 || c.Cel.KeyColor != 0xF {
+		t.Errorf("got %+v", cmds[3])
+	}
+
+	pic, err := NewPicState(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pic.MonoPalettes()[1] != mono {
+		t.Errorf("got mono palettes %v", pic.MonoPalettes())
+	}
+	if c, ok := pic.MonoColorCode(); !ok || c != 5 {
+		t.Errorf("got mono color code %d, %v", c, ok)
+	}
+	if c := pic.MonoColor(); c != defaultPalette[5] {
+		t.Errorf("got mono color %02x", c)
+	}
+	if p, ok := pic.MonoPriorityCode(); !ok || p != 7 {
+		t.Errorf("got mono priority %d, %v", p, ok)
+	}
+	if pic.PriorityBands() != bands || pic.PriorityBands().Priority(65) != 2 {
+		t.Errorf("got priority bands %v", pic.PriorityBands())
+	}
+
+	visual := pic.Visual().Image().(*image.Paletted)
+	for p, expected := range map[image.Point]uint8{{266, 20}: 1, {267, 20}: 1, {266, 21}: 2, {267, 21}: 0xF} {
+		if c := visual.ColorIndexAt(p.X, p.Y); c != expected {
+			t.Errorf("%v: got %d, expected %d", p, c, expected)
+		}
+	}
+
+	// OPX 0x04 and 0x06 disable the mono codes again
+	disabled := append(append([]byte(nil), b[:len(b)-1]...), 0xFE, 0x04, 0xFE, 0x06, 0xFF)
+	if pic, err = NewPicState(disabled); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pic.MonoColorCode(); ok {
+		t.Error("mono visual is still set")
+	}
+	if _, ok := pic.MonoPriorityCode(); ok {
+		t.Error("mono priority is still set")
+	}
+}
+
+// rawPic is a picture written the way the original tools wrote them, rather
+// than the way EncodePicCommands does: upward jumps too long for a medium
+// delta use long lines, and steps of -7 along x, which would read as an
+// op-code in a short delta, use medium lines.
+var rawPic = func() []byte {
+	palette := defaultPalette
+	palette[3] = 0x33
+
+	b := []byte{0xFE, 0x01, 0x00}
+	b = append(b, palette[:]...)
+	b = append(b,
+		0xF0, 0x03, 0xF2, 0x05,
+		// long lines: 10,180 -> 300,20 -> 300,189
+		0xF6, 0x00, 0x0A, 0xB4, 0x10, 0x2C, 0x14, 0x10, 0x2C, 0xBD,
+		// medium lines: 20,100 -> 13,103 -> 113,13 -> 233,8
+		0xF5, 0x00, 0x14, 0x64, 0x03, 0xF9, 0xDA, 0x64, 0x85, 0x78,
+		// short lines: 150,150 -> 152,153 -> 150,154 -> 157,147 -> 157,144
+		0xF7, 0x00, 0x96, 0x96, 0x23, 0xA1, 0x7F, 0x0B,
+		0xF0, 0x0A,
+		0xF8, 0x00, 0x32, 0x32, 0x00, 0xC8, 0xA0,
+		// textured circles: 319,40 -> 315,41 -> 316,43
+		0xF9, 0x21,
+		0xF4, 0x10, 0x10, 0x3F, 0x28, 0x06, 0xC1, 0xEE, 0x12,
+		// 100,100 -> 90,90
+		0xFD, 0x20, 0x00, 0x64, 0x64, 0x30, 0x8A, 0xF6,
+		// solid rectangles
+		0xFB, 0x04, 0xF9, 0x10,
+		0xFA, 0x00, 0x28, 0xAA, 0x00, 0x2A, 0xAB,
+		0xFC, 0xF1, 0xF3,
+		0xFF,
+	)
+	return b
+}()
+
+// withoutOffsets clears the offset of every command, so that the commands of
+// two differently encoded pictures can be compared.
+func withoutOffsets(cmds []PicCommand) []PicCommand {
+	var cleared []PicCommand
+	for _, cmd := range cmds {
+		v := reflect.New(reflect.TypeOf(cmd)).Elem()
+		v.Set(reflect.ValueOf(cmd))
+		v.FieldByName("PicOffset").SetInt(0)
+		cleared = append(cleared, v.Interface().(PicCommand))
+	}
+	return cleared
+}
+
+func TestEncodeRawPicRoundTrip(t *testing.T) {
+	cmds, err := DecodePicCommands(rawPic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := EncodePicCommands(cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodePicCommands(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := withoutOffsets(cmds), withoutOffsets(decoded); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("got commands\n%v\nexpected\n%v", actual, expected)
+	}
+
+	reencoded, err := EncodePicCommands(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, reencoded) {
+		t.Errorf("re-encoded picture differs:\n% x\n% x", encoded, reencoded)
+	}
+
+	expected, err := NewPic(rawPic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := NewPic(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := map[string][2]screen.Buffer{
+		"visual":   {expected.Visual(), actual.Visual()},
+		"priority": {expected.Priority(), actual.Priority()},
+		"control":  {expected.Control(), actual.Control()},
+	}
+	for name, l := range layers {
+		e, a := l[0].Image().(*image.Paletted), l[1].Image().(*image.Paletted)
+		if !bytes.Equal(e.Pix, a.Pix) {
+			t.Errorf("%s layer differs", name)
+		}
+	}
+}
+
+func TestPicSnapshot(t *testing.T) {
+	b := []byte{
+		0xFE, 0x00, 0x02, 0x4C, // palette 0, entry 2
+		0xF0, 0x02, 0xF2, 0x05, 0xFB, 0x03,
+		0xF9, 0x23,
+		0xF3,
+		0xFF,
+	}
+	pic, err := NewPicState(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := pic.Snapshot()
+	if s.DrawMode != PicDrawVisual|PicDrawControl {
+		t.Errorf("got draw mode %03b", s.DrawMode)
+	}
+	if s.Palettes[0][2] != 0x4C || s.Palettes[1] != defaultPalette {
+		t.Errorf("got palettes %v", s.Palettes)
+	}
+	if s.ColorCode != 2 || s.Color != 0x4C {
+		t.Errorf("got color code %d, color %02x", s.ColorCode, s.Color)
+	}
+	if c1, c2 := s.EGA(); c1 != 0xC || c2 != 0x4 {
+		t.Errorf("got EGA colors %d, %d", c1, c2)
+	}
+	if s.PriorityCode != 5 || s.ControlCode != 3 {
+		t.Errorf("got priority %d, control %d", s.PriorityCode, s.ControlCode)
+	}
+	if s.PatternCode != 0x23 || s.PatternCode.Size() != 3 || s.PatternCode.IsRect() || s.PatternCode.IsSolid() {
+		t.Errorf("got pattern code %02x", s.PatternCode)
+	}
+}
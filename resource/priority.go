@@ -0,0 +1,50 @@
+package resource
+
+// PriorityBands holds the top y-coordinate of each of the 14 priority
+// bands of a picture. Anything above the first band has priority 0.
+type PriorityBands [14]uint8
+
+// DefaultPriorityBands are the bands used by the SCI0 interpreter when
+// the picture does not define its own: 14 equal bands between
+// y=42 and y=190.
+var DefaultPriorityBands = func() PriorityBands {
+	const top, bottom, count = 42, 190, 14
+
+	// The interpreter computes this with fixed-point integers. Doing it
+	// any other way will shift the band edges by a pixel.
+	size := ((bottom - top) * 2000) / count
+
+	var bands PriorityBands
+	band := 0
+	for y := top; y < bottom && band < count; y++ {
+		if p := 1 + ((y-top)*2000)/size; p > band {
+			bands[band] = uint8(y)
+			band++
+		}
+	}
+	return bands
+}()
+
+// Priority returns the priority of the band that contains y.
+func (bands PriorityBands) Priority(y int) uint8 {
+	var p uint8
+	for _, top := range bands {
+		if y < int(top) {
+			break
+		}
+		p++
+	}
+	return p
+}
+
+// Top returns the first y-coordinate that has the given priority.
+func (bands PriorityBands) Top(priority uint8) int {
+	switch {
+	case priority == 0:
+		return 0
+	case int(priority) > len(bands):
+		return int(bands[len(bands)-1])
+	default:
+		return int(bands[priority-1])
+	}
+}
@@ -0,0 +1,279 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ScriptBlockType identifies the contents of a block of a script resource.
+type ScriptBlockType uint16
+
+const (
+	ScriptBlockEnd ScriptBlockType = iota
+	ScriptBlockObject
+	ScriptBlockCode
+	ScriptBlockSynonyms
+	ScriptBlockSaid
+	ScriptBlockStrings
+	ScriptBlockClass
+	ScriptBlockExports
+	ScriptBlockRelocation
+	ScriptBlockPreloadText
+	ScriptBlockLocals
+)
+
+// ScriptBlock is a single block of a script resource.
+type ScriptBlock struct {
+	Type ScriptBlockType
+	// Offset is the position of the block's header within the script.
+	Offset int
+	Data   []byte
+}
+
+// Script is a script resource, split into its blocks. Offsets within the
+// script are counted from the first block.
+type Script struct {
+	// Prefix holds the local variable count that precedes the blocks of
+	// early SCI0 scripts.
+	Prefix []byte
+	Blocks []ScriptBlock
+	// Trailer is everything after the type of the final block.
+	Trailer []byte
+}
+
+// NewScript splits a script resource into its blocks.
+func NewScript(b []byte) (*Script, error) {
+	script, err := readScriptBlocks(b, 0)
+	if err != nil && len(b) >= 2 {
+		if early, earlyErr := readScriptBlocks(b, 2); earlyErr == nil {
+			return early, nil
+		}
+	}
+	return script, err
+}
+
+func readScriptBlocks(b []byte, prefix int) (*Script, error) {
+	script := &Script{Prefix: b[:prefix]}
+	body := b[prefix:]
+	for offset := 0; ; {
+		if offset+2 > len(body) {
+			return nil, errors.New("script has no final block")
+		}
+		t := ScriptBlockType(binary.LittleEndian.Uint16(body[offset:]))
+		if t == ScriptBlockEnd {
+			script.Trailer = body[offset+2:]
+			return script, nil
+		}
+		if t > ScriptBlockLocals || offset+4 > len(body) {
+			return nil, fmt.Errorf("invalid block at %d", offset)
+		}
+		size := int(binary.LittleEndian.Uint16(body[offset+2:]))
+		if size < 4 || offset+size > len(body) {
+			return nil, fmt.Errorf("invalid block size at %d", offset)
+		}
+		script.Blocks = append(script.Blocks, ScriptBlock{
+			Type:   t,
+			Offset: offset,
+			Data:   body[offset+4 : offset+size],
+		})
+		offset += size
+	}
+}
+
+// Encode packs the blocks back into a script resource. The blocks are written
+// in order, and each Offset is updated to where its block was written.
+func (s *Script) Encode() ([]byte, error) {
+	var w bytes.Buffer
+	w.Write(s.Prefix)
+	for i := range s.Blocks {
+		block := &s.Blocks[i]
+		if len(block.Data)+4 > 0xFFFF {
+			return nil, fmt.Errorf("block %d is too large", i)
+		}
+		block.Offset = w.Len() - len(s.Prefix)
+		binary.Write(&w, binary.LittleEndian, [2]uint16{uint16(block.Type), uint16(len(block.Data) + 4)})
+		w.Write(block.Data)
+	}
+	binary.Write(&w, binary.LittleEndian, uint16(ScriptBlockEnd))
+	w.Write(s.Trailer)
+	return w.Bytes(), nil
+}
+
+// ScriptString is a NUL-terminated string of a strings block.
+type ScriptString struct {
+	// Offset is the position of the string within the script.
+	Offset int
+	Value  string
+}
+
+// Strings returns every string of the block. Blocks of other types have no
+// strings.
+func (b ScriptBlock) Strings() []ScriptString {
+	if b.Type != ScriptBlockStrings {
+		return nil
+	}
+	var strs []ScriptString
+	for start := 0; start < len(b.Data); {
+		end := bytes.IndexByte(b.Data[start:], 0x00)
+		if end < 0 {
+			end = len(b.Data) - start
+		}
+		if end > 0 {
+			strs = append(strs, ScriptString{
+				Offset: b.Offset + 4 + start,
+				Value:  string(b.Data[start : start+end]),
+			})
+		}
+		start += end + 1
+	}
+	return strs
+}
+
+// ScriptSaid is a said spec of a said block.
+type ScriptSaid struct {
+	// Offset is the position of the said spec within the script.
+	Offset int
+	// Data is the said spec, without its terminator.
+	Data []byte
+}
+
+// Saids returns every said spec of the block, see Vocabulary.DecodeSaid.
+// Blocks of other types have no said specs.
+func (b ScriptBlock) Saids() []ScriptSaid {
+	if b.Type != ScriptBlockSaid {
+		return nil
+	}
+	var saids []ScriptSaid
+	// the second byte of a word's group may be the same as the terminator
+	for start, i := 0, 0; i < len(b.Data); i++ {
+		switch c := b.Data[i]; {
+		case c == saidEnd:
+			if i > start {
+				saids = append(saids, ScriptSaid{Offset: b.Offset + 4 + start, Data: b.Data[start:i]})
+			}
+			start = i + 1
+		case c < saidOperator:
+			i++
+		}
+	}
+	return saids
+}
+
+// ScriptInstruction is a single decoded instruction of a code block.
+type ScriptInstruction struct {
+	// Offset is the position of the instruction within the script.
+	Offset   int
+	Opcode   uint8
+	Operands []int
+	// OperandOffsets is the position of each operand within the script.
+	OperandOffsets []int
+	Size           int
+}
+
+// Op returns the operation of the instruction, without its operand size.
+func (in ScriptInstruction) Op() uint8 { return in.Opcode >> 1 }
+
+// Wide reports whether the operands of the instruction are words, rather
+// than bytes.
+func (in ScriptInstruction) Wide() bool { return in.Opcode&1 == 0 }
+
+// Script operations that are used to find references to strings.
+const (
+	ScriptOpCall   = 0x20
+	ScriptOpCallK  = 0x21
+	ScriptOpCallB  = 0x22
+	ScriptOpCallE  = 0x23
+	ScriptOpPushI  = 0x1C
+	ScriptOpLofsa  = 0x39
+	ScriptOpLofss  = 0x3A
+	ScriptOpPush0  = 0x3B
+	ScriptOpPush1  = 0x3C
+	ScriptOpPush2  = 0x3D
+	scriptOpsCount = 0x80
+)
+
+// scriptOperand is the kind of operand of an instruction.
+type scriptOperand uint8
+
+const (
+	// operandVariable is a word, or a byte for the byte form of the op.
+	operandVariable scriptOperand = iota
+	// operandSigned is a signed operandVariable.
+	operandSigned
+	// operandByte is always a single byte.
+	operandByte
+)
+
+var scriptOperands = func() [scriptOpsCount][]scriptOperand {
+	var ops [scriptOpsCount][]scriptOperand
+	v, s, b := operandVariable, operandSigned, operandByte
+	for op := 0x17; op <= 0x19; op++ {
+		ops[op] = []scriptOperand{s} // bt, bnt, jmp
+	}
+	ops[0x1A] = []scriptOperand{s}          // ldi
+	ops[ScriptOpPushI] = []scriptOperand{v} // pushi
+	ops[0x1F] = []scriptOperand{v}          // link
+	ops[ScriptOpCall] = []scriptOperand{s, b}
+	ops[ScriptOpCallK] = []scriptOperand{v, b}
+	ops[ScriptOpCallB] = []scriptOperand{v, b}
+	ops[ScriptOpCallE] = []scriptOperand{v, v, b}
+	ops[0x25] = []scriptOperand{b}    // send
+	ops[0x28] = []scriptOperand{v}    // class
+	ops[0x2A] = []scriptOperand{b}    // self
+	ops[0x2B] = []scriptOperand{v, b} // super
+	ops[0x2C] = []scriptOperand{v}    // &rest
+	ops[0x2D] = []scriptOperand{v, v} // lea
+	for op := 0x31; op <= 0x38; op++ {
+		ops[op] = []scriptOperand{v} // property access
+	}
+	ops[ScriptOpLofsa] = []scriptOperand{s}
+	ops[ScriptOpLofss] = []scriptOperand{s}
+	for op := 0x40; op < scriptOpsCount; op++ {
+		ops[op] = []scriptOperand{v} // variable access
+	}
+	return ops
+}()
+
+// Instructions decodes every instruction of a code block. Blocks of other
+// types have no instructions.
+func (b ScriptBlock) Instructions() ([]ScriptInstruction, error) {
+	if b.Type != ScriptBlockCode {
+		return nil, nil
+	}
+
+	var instructions []ScriptInstruction
+	for pc := 0; pc < len(b.Data); {
+		in := ScriptInstruction{Offset: b.Offset + 4 + pc, Opcode: b.Data[pc]}
+		at := pc + 1
+		for _, kind := range scriptOperands[in.Op()] {
+			size := 1
+			if kind != operandByte && in.Wide() {
+				size = 2
+			}
+			if at+size > len(b.Data) {
+				return instructions, fmt.Errorf("truncated instruction at %d", in.Offset)
+			}
+
+			var value int
+			switch {
+			case size == 2 && kind == operandSigned:
+				value = int(int16(binary.LittleEndian.Uint16(b.Data[at:])))
+			case size == 2:
+				value = int(binary.LittleEndian.Uint16(b.Data[at:]))
+			case kind == operandSigned:
+				value = int(int8(b.Data[at]))
+			default:
+				value = int(b.Data[at])
+			}
+			in.Operands = append(in.Operands, value)
+			in.OperandOffsets = append(in.OperandOffsets, b.Offset+4+at)
+			at += size
+		}
+		in.Size = at - pc
+		instructions = append(instructions, in)
+		pc = at
+	}
+	return instructions, nil
+}
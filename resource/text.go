@@ -3,7 +3,11 @@ package resource
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
 )
 
 type Text []string
@@ -24,3 +28,46 @@ func NewText(b []byte) (Text, error) {
 
 	return text, nil
 }
+
+// Encode packs the text into a text resource.
+func (t Text) Encode() ([]byte, error) {
+	var b bytes.Buffer
+	for i, str := range t {
+		if strings.IndexByte(str, 0x00) >= 0 {
+			return nil, fmt.Errorf("string %d contains a NUL", i)
+		}
+		b.WriteString(str)
+		b.WriteByte(0x00)
+	}
+	return b.Bytes(), nil
+}
+
+// Decode converts each string of the text from the DOS code page enc, such as
+// charmap.CodePage437, to UTF-8.
+func (t Text) Decode(enc encoding.Encoding) ([]string, error) {
+	dec := enc.NewDecoder()
+	result := make([]string, len(t))
+	for i, str := range t {
+		s, err := dec.String(str)
+		if err != nil {
+			return nil, fmt.Errorf("string %d: %w", i, err)
+		}
+		result[i] = s
+	}
+	return result, nil
+}
+
+// EncodeText converts UTF-8 strings to the DOS code page enc. It is the
+// inverse of Text.Decode.
+func EncodeText(strs []string, enc encoding.Encoding) (Text, error) {
+	e := enc.NewEncoder()
+	text := make(Text, len(strs))
+	for i, str := range strs {
+		s, err := e.String(str)
+		if err != nil {
+			return nil, fmt.Errorf("string %d: %w", i, err)
+		}
+		text[i] = s
+	}
+	return text, nil
+}
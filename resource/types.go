@@ -1,5 +1,7 @@
 package resource
 
+import "fmt"
+
 type Type uint8
 
 const (
@@ -40,3 +42,26 @@ func (t Type) String() string {
 	}
 	return "Type(UNKNOWN)"
 }
+
+var patchNames = map[Type]string{
+	TypeView:   "VIEW",
+	TypePic:    "PIC",
+	TypeScript: "SCRIPT",
+	TypeText:   "TEXT",
+	TypeSound:  "SOUND",
+	TypeMemory: "MEMORY",
+	TypeVocab:  "VOCAB",
+	TypeFont:   "FONT",
+	TypeCursor: "CURSOR",
+	TypePatch:  "PATCH",
+}
+
+// PatchName returns the name of the patch file for a resource of the type,
+// such as TEXT.001, that the interpreter loads in place of the resource.
+func (t Type) PatchName(n Number) string {
+	name, ok := patchNames[t]
+	if !ok {
+		name = fmt.Sprintf("TYPE%d", t)
+	}
+	return fmt.Sprintf("%s.%03d", name, n)
+}
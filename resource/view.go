@@ -5,12 +5,23 @@ import (
 	"encoding/binary"
 	"image"
 	"image/color"
-	"image/gif"
+	"io"
 
 	"github.com/32bitkid/sci/screen"
 )
 
+// NewView decodes a view resource. Loops that are mirrors of another loop
+// point at the same cels as that loop, and share the pixels of those cels
+// rather than a copy of them. NewViewInfo also returns which loop each
+// mirrored loop comes from.
 func NewView(b []byte) (View, error) {
+	info, err := NewViewInfo(b)
+	return info.View, err
+}
+
+// NewViewInfo decodes a view resource, like NewView, and records the loop
+// that each mirrored loop points at.
+func NewViewInfo(b []byte) (ViewInfo, error) {
 	r := bytes.NewReader(b)
 
 	var header struct {
@@ -20,19 +31,26 @@ func NewView(b []byte) (View, error) {
 	}
 
 	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
-		return nil, err
+		return ViewInfo{}, err
+	}
+
+	mirrored := func(g int) bool {
+		return header.Mirrored&(1<<uint(g)) == (1 << uint(g))
 	}
 
-	view := make(View, 0, header.Groups)
+	info := ViewInfo{View: make(View, 0, header.Groups)}
 
 	groupPointers := make([]uint16, header.Groups)
 	if err := binary.Read(r, binary.LittleEndian, &groupPointers); err != nil {
-		return nil, err
+		return ViewInfo{}, err
 	}
 
+	// cels that have already been decoded, by their offset
+	cels := map[uint16]Sprite{}
+
 	for g, groupPointer := range groupPointers {
 		if _, err := r.Seek(int64(groupPointer), 0); err != nil {
-			return nil, err
+			return ViewInfo{}, err
 		}
 
 		var groupHeader struct {
@@ -41,60 +59,142 @@ func NewView(b []byte) (View, error) {
 		}
 
 		if err := binary.Read(r, binary.LittleEndian, &groupHeader); err != nil {
-			return nil, err
+			return ViewInfo{}, err
 		}
 
-		mirrored := header.Mirrored&(1<<uint(g)) == (1 << uint(g))
-
 		group := SpriteGroup{}
 
 		spritePointers := make([]uint16, groupHeader.Images)
 		if err := binary.Read(r, binary.LittleEndian, &spritePointers); err != nil {
-			return nil, err
+			return ViewInfo{}, err
 		}
 
 		for _, spritePointer := range spritePointers {
+			if sprite, ok := cels[spritePointer]; ok {
+				sprite.Mirrored = mirrored(g)
+				group = append(group, sprite)
+				continue
+			}
+
 			if _, err := r.Seek(int64(spritePointer), 0); err != nil {
-				return nil, err
+				return ViewInfo{}, err
 			}
 
 			sprite := Sprite{}
 
 			if err := binary.Read(r, binary.LittleEndian, &sprite.SpriteHeader); err != nil {
-				return nil, err
+				return ViewInfo{}, err
 			}
 
-			total := int(sprite.Width * sprite.Height)
-			bitmap := make([]uint8, total)
-			i := 0
-			for i < total {
-				var b uint8
-				err := binary.Read(r, binary.LittleEndian, &b)
-				if err != nil {
-					return nil, err
-				}
-				color := b & 0xF
-				repeat := int(b >> 4)
-				for r := 0; r < repeat; r++ {
-					bitmap[i] = color
-					i++
-				}
+			bitmap, err := readSpritePixels(r, int(sprite.Width)*int(sprite.Height))
+			if err != nil {
+				return ViewInfo{}, err
 			}
 
 			sprite.Pixels = bitmap
-			sprite.Mirrored = mirrored
+			cels[spritePointer] = sprite
+
+			sprite.Mirrored = mirrored(g)
 			group = append(group, sprite)
 		}
-		view = append(view, group)
+		info.View = append(info.View, group)
+	}
+
+	// a mirrored loop comes from the first loop that is not mirrored, and
+	// has the same group pointer
+	for g, groupPointer := range groupPointers {
+		if !mirrored(g) {
+			continue
+		}
+		for source, p := range groupPointers {
+			if p == groupPointer && !mirrored(source) {
+				if info.Mirrors == nil {
+					info.Mirrors = map[int]int{}
+				}
+				info.Mirrors[g] = source
+				break
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// readSpritePixels decodes total pixels of run-length encoded cel data. Each
+// byte holds a repeat count in the high nibble and a color in the low nibble.
+func readSpritePixels(r io.Reader, total int) ([]uint8, error) {
+	bitmap := make([]uint8, total)
+	i := 0
+	for i < total {
+		var b uint8
+		err := binary.Read(r, binary.LittleEndian, &b)
+		if err != nil {
+			return nil, err
+		}
+		color := b & 0xF
+		repeat := int(b >> 4)
+		for r := 0; r < repeat && i < total; r++ {
+			bitmap[i] = color
+			i++
+		}
 	}
+	return bitmap, nil
+}
 
-	return view, nil
+// writeSpritePixels run-length encodes the pixels of a cel that is width
+// pixels wide. Runs never cross the end of a row.
+func writeSpritePixels(w io.ByteWriter, pixels []uint8, width int) error {
+	if width <= 0 {
+		return nil
+	}
+	for row := 0; row < len(pixels); row += width {
+		line := pixels[row : row+width]
+		for i := 0; i < len(line); {
+			color, repeat := line[i], 1
+			for i+repeat < len(line) && repeat < 0xF && line[i+repeat] == color {
+				repeat++
+			}
+			if err := w.WriteByte(uint8(repeat<<4) | color&0xF); err != nil {
+				return err
+			}
+			i += repeat
+		}
+	}
+	return nil
 }
 
 type View []SpriteGroup
 
+// ViewInfo is a view, and the loops that its mirrored loops come from.
+type ViewInfo struct {
+	View
+
+	// Mirrors maps each mirrored loop to the loop that it is a mirror of.
+	// The cels of both loops are stored once in a view resource.
+	Mirrors map[int]int
+}
+
+// MirrorOf returns the loop that a mirrored loop is a mirror of. It returns
+// false if the loop is not mirrored, or is not a mirror of a loop that is
+// drawn unmirrored.
+func (info ViewInfo) MirrorOf(loop int) (int, bool) {
+	view := info.View
+	if loop < 0 || loop >= len(view) || !view[loop].mirrored() {
+		return 0, false
+	}
+	source, ok := info.Mirrors[loop]
+	if !ok || source < 0 || source >= len(view) || view[source].mirrored() {
+		return 0, false
+	}
+	return source, true
+}
+
 type SpriteGroup []Sprite
 
+func (group SpriteGroup) mirrored() bool {
+	return len(group) > 0 && group[0].Mirrored
+}
+
 type SpriteHeader struct {
 	Width    uint16
 	Height   uint16
@@ -109,81 +209,46 @@ type Sprite struct {
 	Pixels   []uint8
 }
 
-func (group SpriteGroup) GIF(d *screen.Ditherer) *gif.GIF {
-	palette := make(color.Palette, 256)
-	for i := 0; i < 256; i++ {
-		palette[i] = color.Gray{}
+// Rect returns the area covered by the cel when it is drawn with its origin
+// at x, y. The origin is the bottom-center of the cel, moved by the cel's
+// displacement, and is right of the middle for cels of an even width.
+// Mirrored cels are also displaced in the mirrored direction.
+func (s Sprite) Rect(x, y int) image.Rectangle {
+	dx := int(s.X)
+	if s.Mirrored {
+		dx = -dx
 	}
-	copy(palette, d.Palette)
-
-	palette[0xff] = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
-
-	var images []*image.Paletted
-	var delays []int
-	var dispose []byte
-
-	// FIXME this bounds checking isn't correct
-	rect := image.Rectangle{}
-	for _, s := range group {
-		if int(s.X) < rect.Min.X {
-			rect.Min.X = int(s.X)
-		}
-
-		if int(s.Y) < rect.Min.Y {
-			rect.Min.Y = int(s.Y)
-		}
+	left := x + dx - int(s.Width)>>1
+	bottom := y + int(s.Y) + 1
+	return image.Rect(left, bottom-int(s.Height), left+int(s.Width), bottom)
+}
 
-		if rect.Max.X < (int(s.Width) + int(s.X)) {
-			rect.Max.X = int(s.Width) + int(s.X)
-		}
-		if rect.Max.Y < (int(s.Height) + int(s.Y)) {
-			rect.Max.Y = int(s.Height) + int(s.Y)
-		}
+// At returns the color of the cel at x, y, counted from its top-left corner,
+// taking into account whether the cel is mirrored.
+func (s Sprite) At(x, y int) uint8 {
+	if s.Mirrored {
+		x = int(s.Width) - 1 - x
 	}
+	return s.Pixels[y*int(s.Width)+x]
+}
 
-	offset := rect.Min
-	rect = rect.Sub(offset)
-	rect.Max.X *= 5
-	rect.Max.Y *= 6
-
-	for _, s := range group {
-		img := image.NewPaletted(rect, palette)
-		for i := range img.Pix {
-			img.Pix[i] = 0xff
-		}
-
-		stride := int(s.Width)
-		ox := int(s.X) - offset.X
-		oy := int(s.Y) - offset.Y
-
-		for y := 0; y < int(s.Height); y++ {
-			for x := 0; x < stride; x += 1 {
-				ax := ox + x
-				if s.Mirrored {
-					ax = stride - 1 - x
-				}
-				srcPix := s.Pixels[x+(y*stride)]
-				if srcPix == s.KeyColor {
-					continue
-				}
-				for dy := (oy + y) * 6; dy < ((oy+y)*6)+6; dy++ {
-					for dx := ax * 5; dx < (ax*5)+5; dx++ {
-						img.Pix[dx+(dy*img.Stride)] = srcPix
-					}
-				}
-
+// Image returns the cel as an image with the EGA palette. Pixels of the key
+// color are transparent.
+func (s Sprite) Image() *image.Paletted {
+	pal := make(color.Palette, transparentIndex+1)
+	copy(pal, screen.DefaultPalettes.EGA)
+	pal[transparentIndex] = color.Transparent
+
+	img := image.NewPaletted(image.Rect(0, 0, int(s.Width), int(s.Height)), pal)
+	for y := 0; y < int(s.Height); y++ {
+		for x := 0; x < int(s.Width); x++ {
+			c := s.At(x, y)
+			if c == s.KeyColor {
+				img.Pix[y*img.Stride+x] = transparentIndex
+			} else {
+				img.Pix[y*img.Stride+x] = c & 0xF
 			}
 		}
-
-		images = append(images, img)
-		delays = append(delays, 20)
-		dispose = append(dispose, gif.DisposalBackground)
-	}
-
-	return &gif.GIF{
-		Image:           images,
-		Delay:           delays,
-		Disposal:        dispose,
-		BackgroundIndex: 255,
 	}
+	return img
 }
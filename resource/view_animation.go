@@ -0,0 +1,280 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+
+	"github.com/32bitkid/sci/screen"
+)
+
+// AnimationOptions control how the cels of a SpriteGroup are rendered.
+type AnimationOptions struct {
+	// Ditherer provides the EGA palette. Defaults to screen.DefaultDitherers.EGA.
+	*screen.Ditherer
+
+	// ScaleX and ScaleY are the size of each pixel. Defaults to 1x1.
+	ScaleX, ScaleY int
+
+	// Delay is the time between frames, in 100ths of a second. Defaults to 20.
+	Delay int
+}
+
+func (opts AnimationOptions) scale() (int, int) {
+	sx, sy := opts.ScaleX, opts.ScaleY
+	if sx <= 0 {
+		sx = 1
+	}
+	if sy <= 0 {
+		sy = 1
+	}
+	return sx, sy
+}
+
+func (opts AnimationOptions) delay() int {
+	if opts.Delay <= 0 {
+		return 20
+	}
+	return opts.Delay
+}
+
+// transparentIndex is the palette index of the transparent background of
+// rendered cels. Indices below it are the 16 EGA colors.
+const transparentIndex = 16
+
+func (opts AnimationOptions) palette() color.Palette {
+	d := opts.Ditherer
+	if d == nil {
+		d = screen.DefaultDitherers.EGA
+	}
+	pal := make(color.Palette, transparentIndex+1)
+	for i := range pal {
+		pal[i] = color.Black
+	}
+	copy(pal[:transparentIndex], d.Palette)
+	pal[transparentIndex] = color.Transparent
+	return pal
+}
+
+// Bounds returns the smallest rectangle that contains every cel of the
+// group, relative to the shared origin of the cels.
+func (group SpriteGroup) Bounds() image.Rectangle {
+	var bounds image.Rectangle
+	for _, s := range group {
+		bounds = bounds.Union(s.Rect(0, 0))
+	}
+	return bounds
+}
+
+// Frames renders each cel of the group, positioned relative to the other cels.
+func (group SpriteGroup) Frames(opts AnimationOptions) []*image.Paletted {
+	var (
+		bounds = group.Bounds()
+		pal    = opts.palette()
+		sx, sy = opts.scale()
+		rect   = image.Rect(0, 0, bounds.Dx()*sx, bounds.Dy()*sy)
+	)
+
+	frames := make([]*image.Paletted, 0, len(group))
+	for _, s := range group {
+		img := image.NewPaletted(rect, pal)
+		for i := range img.Pix {
+			img.Pix[i] = transparentIndex
+		}
+
+		at := s.Rect(0, 0).Min.Sub(bounds.Min)
+		for y := 0; y < int(s.Height); y++ {
+			for x := 0; x < int(s.Width); x++ {
+				c := s.At(x, y)
+				if c == s.KeyColor {
+					continue
+				}
+
+				px, py := (at.X+x)*sx, (at.Y+y)*sy
+				for dy := py; dy < py+sy; dy++ {
+					for dx := px; dx < px+sx; dx++ {
+						img.Pix[dy*img.Stride+dx] = c & 0xF
+					}
+				}
+			}
+		}
+		frames = append(frames, img)
+	}
+
+	return frames
+}
+
+// GIF renders the group as an animated GIF.
+func (group SpriteGroup) GIF(opts AnimationOptions) *gif.GIF {
+	frames := group.Frames(opts)
+	anim := &gif.GIF{
+		Image:           frames,
+		Delay:           make([]int, len(frames)),
+		Disposal:        make([]byte, len(frames)),
+		BackgroundIndex: transparentIndex,
+	}
+	for i := range frames {
+		anim.Delay[i] = opts.delay()
+		anim.Disposal[i] = gif.DisposalBackground
+	}
+	return anim
+}
+
+// SpriteSheet renders every cel of the group side by side, in a single
+// image. Each cel occupies a cell the size of the group's Bounds.
+func (group SpriteGroup) SpriteSheet(opts AnimationOptions) *image.Paletted {
+	frames := group.Frames(opts)
+	if len(frames) == 0 {
+		return image.NewPaletted(image.Rectangle{}, opts.palette())
+	}
+
+	cell := frames[0].Rect
+	sheet := image.NewPaletted(image.Rect(0, 0, cell.Dx()*len(frames), cell.Dy()), frames[0].Palette)
+	for i, frame := range frames {
+		for y := 0; y < cell.Dy(); y++ {
+			src := frame.Pix[y*frame.Stride : y*frame.Stride+cell.Dx()]
+			copy(sheet.Pix[y*sheet.Stride+i*cell.Dx():], src)
+		}
+	}
+	return sheet
+}
+
+// APNG writes the group as an animated PNG.
+func (group SpriteGroup) APNG(w io.Writer, opts AnimationOptions) error {
+	frames := group.Frames(opts)
+	if len(frames) == 0 {
+		return errors.New("no cels to animate")
+	}
+
+	delays := make([]int, len(frames))
+	for i := range delays {
+		delays[i] = opts.delay()
+	}
+	return encodeAPNG(w, frames, delays)
+}
+
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+// readPNGChunks splits an encoded PNG into its chunks.
+func readPNGChunks(b []byte) ([]pngChunk, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(b, []byte(signature)) {
+		return nil, errors.New("invalid PNG signature")
+	}
+	b = b[len(signature):]
+
+	var chunks []pngChunk
+	for len(b) >= 12 {
+		length := int(binary.BigEndian.Uint32(b))
+		if len(b) < 12+length {
+			return nil, errors.New("truncated PNG chunk")
+		}
+		chunks = append(chunks, pngChunk{
+			kind: string(b[4:8]),
+			data: b[8 : 8+length],
+		})
+		b = b[12+length:]
+	}
+	return chunks, nil
+}
+
+func writePNGChunk(w io.Writer, kind string, data []byte) error {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], kind)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+
+	var footer [4]byte
+	binary.BigEndian.PutUint32(footer[:], crc.Sum32())
+
+	for _, b := range [][]byte{header[:], data, footer[:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeAPNG writes frames as an animated PNG, that loops forever. Every frame
+// must have the same bounds and palette. Delays are in 100ths of a second.
+func encodeAPNG(w io.Writer, frames []*image.Paletted, delays []int) error {
+	if _, err := io.WriteString(w, "\x89PNG\r\n\x1a\n"); err != nil {
+		return err
+	}
+
+	var seq uint32
+	for i, frame := range frames {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, frame); err != nil {
+			return err
+		}
+		chunks, err := readPNGChunks(buf.Bytes())
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			for _, c := range chunks {
+				if c.kind == "IDAT" || c.kind == "IEND" {
+					continue
+				}
+				if err := writePNGChunk(w, c.kind, c.data); err != nil {
+					return err
+				}
+			}
+
+			var actl [8]byte
+			binary.BigEndian.PutUint32(actl[0:], uint32(len(frames)))
+			binary.BigEndian.PutUint32(actl[4:], 0)
+			if err := writePNGChunk(w, "acTL", actl[:]); err != nil {
+				return err
+			}
+		}
+
+		const disposeBackground, blendSource = 1, 0
+		var fctl [26]byte
+		binary.BigEndian.PutUint32(fctl[0:], seq)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(frame.Rect.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(frame.Rect.Dy()))
+		binary.BigEndian.PutUint16(fctl[20:], uint16(delays[i]))
+		binary.BigEndian.PutUint16(fctl[22:], 100)
+		fctl[24] = disposeBackground
+		fctl[25] = blendSource
+		if err := writePNGChunk(w, "fcTL", fctl[:]); err != nil {
+			return err
+		}
+		seq++
+
+		for _, c := range chunks {
+			if c.kind != "IDAT" {
+				continue
+			}
+			if i == 0 {
+				err = writePNGChunk(w, "IDAT", c.data)
+			} else {
+				data := make([]byte, 4+len(c.data))
+				binary.BigEndian.PutUint32(data, seq)
+				copy(data[4:], c.data)
+				err = writePNGChunk(w, "fdAT", data)
+				seq++
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return writePNGChunk(w, "IEND", nil)
+}
@@ -0,0 +1,187 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/32bitkid/sci/screen"
+)
+
+// AutoKeyColor can be used as the KeyColor of a cel, and as the color of its
+// transparent pixels, when the cel does not have a key color of its own. The
+// encoder replaces it with a color that the cel does not use.
+const AutoKeyColor uint8 = 0xFF
+
+// NewSprite converts an image into a cel. Every opaque pixel must be one of
+// the 16 EGA colors. Transparent pixels are given the AutoKeyColor.
+func NewSprite(img image.Image, x, y int8) (Sprite, error) {
+	bounds := img.Bounds()
+	if bounds.Dx() > 0xFFFF || bounds.Dy() > 0xFFFF {
+		return Sprite{}, errors.New("image is too large")
+	}
+
+	sprite := Sprite{
+		SpriteHeader: SpriteHeader{
+			Width:    uint16(bounds.Dx()),
+			Height:   uint16(bounds.Dy()),
+			X:        x,
+			Y:        y,
+			KeyColor: AutoKeyColor,
+		},
+		Pixels: make([]uint8, 0, bounds.Dx()*bounds.Dy()),
+	}
+
+	ega := screen.DefaultPalettes.EGA
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			c := img.At(px, py)
+			if _, _, _, a := c.RGBA(); a < 0x8000 {
+				sprite.Pixels = append(sprite.Pixels, AutoKeyColor)
+				continue
+			}
+			i := ega.Index(c)
+			if !sameColor(c, ega[i]) {
+				return Sprite{}, fmt.Errorf("color at %d,%d is not an EGA color", px, py)
+			}
+			sprite.Pixels = append(sprite.Pixels, uint8(i))
+		}
+	}
+	return sprite, nil
+}
+
+func sameColor(a, b color.Color) bool {
+	r1, g1, b1, _ := a.RGBA()
+	r2, g2, b2, _ := b.RGBA()
+	return r1>>8 == r2>>8 && g1>>8 == g2>>8 && b1>>8 == b2>>8
+}
+
+// Encode packs the view into a view resource. Every loop stores its own
+// cels, see ViewInfo.Encode to store mirrored loops once. Cels with the
+// AutoKeyColor are given a key color that they do not otherwise use.
+func (view View) Encode() ([]byte, error) {
+	return ViewInfo{View: view}.Encode()
+}
+
+// Encode packs the view into a view resource. Mirrored loops that are a
+// mirror of another loop, see MirrorOf, point at the cels of that loop instead
+// of storing a copy of them. Cels with the AutoKeyColor are given a key color
+// that they do not otherwise use.
+func (info ViewInfo) Encode() ([]byte, error) {
+	view := info.View
+	if len(view) > 16 {
+		return nil, fmt.Errorf("view has %d loops, at most 16 are allowed", len(view))
+	}
+
+	var mirrored uint16
+	for g, group := range view {
+		if group.mirrored() {
+			mirrored |= 1 << uint(g)
+		}
+	}
+
+	var w bytes.Buffer
+	binary.Write(&w, binary.LittleEndian, [2]uint16{uint16(len(view)), mirrored})
+	w.Write(make([]byte, 4))
+
+	pointers := make([]uint16, len(view))
+	w.Write(make([]byte, 2*len(view)))
+
+	for g, group := range view {
+		if _, ok := info.MirrorOf(g); ok {
+			continue
+		}
+		if w.Len() > 0xFFFF {
+			return nil, errors.New("view is too large")
+		}
+		pointers[g] = uint16(w.Len())
+
+		if len(group) > 0xFFFF {
+			return nil, fmt.Errorf("loop %d has too many cels", g)
+		}
+		binary.Write(&w, binary.LittleEndian, [2]uint16{uint16(len(group)), 0})
+		celPointers := w.Len()
+		w.Write(make([]byte, 2*len(group)))
+
+		for c, cel := range group {
+			if w.Len() > 0xFFFF {
+				return nil, errors.New("view is too large")
+			}
+			binary.LittleEndian.PutUint16(w.Bytes()[celPointers+2*c:], uint16(w.Len()))
+			if err := writeViewCel(&w, cel); err != nil {
+				return nil, fmt.Errorf("loop %d, cel %d: %w", g, c, err)
+			}
+		}
+	}
+
+	for g := range view {
+		if source, ok := info.MirrorOf(g); ok {
+			pointers[g] = pointers[source]
+		}
+	}
+
+	if w.Len() > 0x10000 {
+		return nil, errors.New("view is too large")
+	}
+
+	b := w.Bytes()
+	for g, p := range pointers {
+		binary.LittleEndian.PutUint16(b[8+2*g:], p)
+	}
+	return b, nil
+}
+
+func writeViewCel(w *bytes.Buffer, cel Sprite) error {
+	if len(cel.Pixels) != int(cel.Width)*int(cel.Height) {
+		return fmt.Errorf("cel has %d pixels, expected %dx%d", len(cel.Pixels), cel.Width, cel.Height)
+	}
+
+	pixels := cel.Pixels
+	if cel.KeyColor == AutoKeyColor {
+		key, ok := unusedColor(pixels)
+		if !ok {
+			return errors.New("cel uses every color, and has no key color")
+		}
+		cel.KeyColor = key
+		pixels = make([]uint8, len(cel.Pixels))
+		for i, c := range cel.Pixels {
+			if c == AutoKeyColor {
+				c = key
+			}
+			pixels[i] = c
+		}
+	}
+
+	if cel.KeyColor > 0xF {
+		return fmt.Errorf("invalid key color %d", cel.KeyColor)
+	}
+	for _, c := range pixels {
+		if c > 0xF {
+			return fmt.Errorf("invalid color %d", c)
+		}
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, cel.SpriteHeader); err != nil {
+		return err
+	}
+	return writeSpritePixels(w, pixels, int(cel.Width))
+}
+
+// unusedColor returns the highest EGA color that does not appear in pixels.
+func unusedColor(pixels []uint8) (uint8, bool) {
+	var used [16]bool
+	for _, c := range pixels {
+		if c <= 0xF {
+			used[c] = true
+		}
+	}
+	for c := 0xF; c >= 0; c-- {
+		if !used[c] {
+			return uint8(c), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,224 @@
+package resource
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	"github.com/32bitkid/sci/screen"
+)
+
+func TestViewEncodeRoundTrip(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	for i, c := range []uint8{1, 1, 2, 0xF, 3, 4, 4, 4, 5, 6, 7, 0xF} {
+		if c != 0xF {
+			img.Set(i%4, i/4, screen.DefaultPalettes.EGA[c])
+		}
+	}
+	walk, err := NewSprite(img, 1, -2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stand := Sprite{
+		SpriteHeader: SpriteHeader{Width: 2, Height: 2, KeyColor: 0},
+		Pixels:       []uint8{0, 9, 9, 0},
+	}
+
+	right := SpriteGroup{walk, stand}
+	left := SpriteGroup{walk, stand}
+	for i := range left {
+		left[i].Mirrored = true
+	}
+	view := ViewInfo{
+		View:    View{right, left, {stand}},
+		Mirrors: map[int]int{1: 0},
+	}
+
+	b, err := view.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := NewViewInfo(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if src, ok := decoded.MirrorOf(1); !ok || src != 0 {
+		t.Errorf("got mirror of %d, %v, expected 0, true", src, ok)
+	}
+	if _, ok := decoded.MirrorOf(2); ok {
+		t.Errorf("loop 2 should not be mirrored")
+	}
+
+	if key := decoded.View[0][0].KeyColor; key != 0xF {
+		t.Errorf("got key color %d, expected 15", key)
+	}
+	if c := decoded.View[1][0].Image().At(0, 0); c != color.Transparent {
+		t.Errorf("got %v, expected transparent", c)
+	}
+	if c := decoded.View[1][0].At(3, 0); c != 1 {
+		t.Errorf("got mirrored color %d, expected 1", c)
+	}
+
+	reencoded, err := decoded.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, reencoded) {
+		t.Errorf("re-encoded view differs:\n% x\n% x", b, reencoded)
+	}
+	if !reflect.DeepEqual(decoded.View[2], SpriteGroup{stand}) {
+		t.Errorf("got %v, expected %v", decoded.View[2], SpriteGroup{stand})
+	}
+}
+
+func TestNewViewMirrors(t *testing.T) {
+	b := []byte{
+		0x03, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00,
+		// loops 0 and 2 are mirrors of loop 1, and loop 0 comes first
+		0x0E, 0x00, 0x0E, 0x00, 0x0E, 0x00,
+		// one cel
+		0x01, 0x00, 0x00, 0x00, 0x14, 0x00,
+		// a 2x1 cel, displaced by 1,0, with key color 15
+		0x02, 0x00, 0x01, 0x00, 0x01, 0x00, 0x0F,
+		0x11, 0x12,
+	}
+	view, err := NewViewInfo(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(view.View) != 3 {
+		t.Fatalf("got %d loops, expected 3", len(view.View))
+	}
+
+	for _, loop := range []int{0, 2} {
+		if source, ok := view.MirrorOf(loop); !ok || source != 1 {
+			t.Errorf("loop %d: got mirror of %d, %v, expected 1, true", loop, source, ok)
+		}
+		cel := view.View[loop][0]
+		if !cel.Mirrored {
+			t.Errorf("loop %d is not mirrored", loop)
+		}
+		if &cel.Pixels[0] != &view.View[1][0].Pixels[0] {
+			t.Errorf("loop %d does not share the pixels of loop 1", loop)
+		}
+		if c := cel.At(0, 0); c != 2 {
+			t.Errorf("loop %d: got color %d, expected 2", loop, c)
+		}
+	}
+	if _, ok := view.MirrorOf(1); ok {
+		t.Error("loop 1 should not be mirrored")
+	}
+	if view.View[1][0].Mirrored {
+		t.Error("the cels of loop 1 should not be mirrored")
+	}
+	if r := view.View[0][0].Rect(10, 10); r != image.Rect(8, 10, 10, 11) {
+		t.Errorf("got %v, expected the displacement to be mirrored", r)
+	}
+
+	reencoded, err := view.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, reencoded) {
+		t.Errorf("re-encoded view differs:\n% x\n% x", b, reencoded)
+	}
+
+	// without the mirror sources, every loop stores its own cels
+	plain, err := NewView(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied, err := plain.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := NewViewInfo(copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Mirrors) != 0 || !reflect.DeepEqual(decoded.View, plain) {
+		t.Errorf("got %v, %v, expected the loops without mirror sources", decoded.View, decoded.Mirrors)
+	}
+}
+
+func TestViewMirrorOf(t *testing.T) {
+	cel := Sprite{SpriteHeader: SpriteHeader{Width: 1, Height: 1}, Pixels: []uint8{1}}
+	mirrored := cel
+	mirrored.Mirrored = true
+
+	view := ViewInfo{
+		View:    View{{cel}, {mirrored}, {mirrored}, {cel}},
+		Mirrors: map[int]int{1: 0, 2: 1, 3: 0},
+	}
+	tests := []struct {
+		loop   int
+		source int
+		ok     bool
+	}{
+		{0, 0, false},
+		{1, 0, true},
+		{2, 0, false}, // a mirror of a mirror
+		{3, 0, false}, // not mirrored
+		{4, 0, false},
+	}
+	for _, tt := range tests {
+		if source, ok := view.MirrorOf(tt.loop); source != tt.source || ok != tt.ok {
+			t.Errorf("loop %d: got %d, %v, expected %d, %v", tt.loop, source, ok, tt.source, tt.ok)
+		}
+	}
+}
+
+func TestSpriteRect(t *testing.T) {
+	tests := []struct {
+		name     string
+		width    uint16
+		dx       int8
+		mirrored bool
+		expected image.Rectangle
+	}{
+		{"even", 4, 0, false, image.Rect(98, 48, 102, 51)},
+		{"odd", 5, 0, false, image.Rect(98, 48, 103, 51)},
+		{"even displaced", 4, 3, false, image.Rect(101, 48, 105, 51)},
+		{"odd displaced", 5, 3, false, image.Rect(101, 48, 106, 51)},
+		{"even mirrored", 4, 3, true, image.Rect(95, 48, 99, 51)},
+		{"odd mirrored", 5, 3, true, image.Rect(95, 48, 100, 51)},
+	}
+	for _, tt := range tests {
+		cel := Sprite{
+			SpriteHeader: SpriteHeader{Width: tt.width, Height: 3, X: tt.dx, Y: -1},
+			Mirrored:     tt.mirrored,
+		}
+		if r := cel.Rect(100, 51); r != tt.expected {
+			t.Errorf("%s: got %v, expected %v", tt.name, r, tt.expected)
+		}
+	}
+}
+
+func TestSpriteMirrored(t *testing.T) {
+	cel := Sprite{
+		SpriteHeader: SpriteHeader{Width: 3, Height: 2, KeyColor: 0xF},
+		Pixels:       []uint8{1, 2, 0xF, 4, 5, 6},
+	}
+	mirrored := cel
+	mirrored.Mirrored = true
+
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			if a, b := cel.At(x, y), mirrored.At(2-x, y); a != b {
+				t.Errorf("%d,%d: got %d, mirrored %d", x, y, a, b)
+			}
+		}
+	}
+	img := mirrored.Image()
+	if c := img.At(0, 0); c != color.Transparent {
+		t.Errorf("got %v, expected the key color to be transparent", c)
+	}
+	if c := img.ColorIndexAt(2, 0); c != 1 {
+		t.Errorf("got %d, expected 1", c)
+	}
+}
@@ -0,0 +1,142 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Word is a word of the parser's vocabulary. Words of the same group are
+// synonyms.
+type Word struct {
+	Text  string
+	Class uint16
+	Group uint16
+}
+
+// Vocabulary is the parser's vocabulary, vocab.000.
+type Vocabulary struct {
+	Words  []Word
+	groups map[uint16]string
+	words  map[string]uint16
+}
+
+// vocabularyIndex is the offset of the first word of each letter, which
+// precedes the words.
+const vocabularyIndex = 26 * 2
+
+// NewVocabulary parses the parser's vocabulary. Each word shares the given
+// number of leading characters with the word before it, and the last of its
+// own characters has the high bit set. Its class and group follow, packed in
+// three bytes.
+func NewVocabulary(b []byte) (*Vocabulary, error) {
+	if len(b) < vocabularyIndex {
+		return nil, errors.New("vocabulary has no index")
+	}
+	v := &Vocabulary{groups: map[uint16]string{}, words: map[string]uint16{}}
+	var word []byte
+	for i := vocabularyIndex; i < len(b); {
+		shared := int(b[i])
+		if shared > len(word) {
+			return nil, fmt.Errorf("invalid word at %d", i)
+		}
+		word = word[:shared]
+		for i++; ; i++ {
+			if i >= len(b) {
+				return nil, errors.New("truncated word")
+			}
+			word = append(word, b[i]&0x7F)
+			if b[i]&0x80 != 0 {
+				break
+			}
+		}
+		i++
+		if i+3 > len(b) {
+			return nil, errors.New("truncated word")
+		}
+		w := Word{
+			Text:  string(word),
+			Class: uint16(b[i])<<4 | uint16(b[i+1])>>4,
+			Group: uint16(b[i+1]&0x0F)<<8 | uint16(b[i+2]),
+		}
+		i += 3
+
+		v.Words = append(v.Words, w)
+		if _, ok := v.groups[w.Group]; !ok {
+			v.groups[w.Group] = w.Text
+		}
+		v.words[w.Text] = w.Group
+	}
+	return v, nil
+}
+
+// Said spec tokens. Bytes below saidOperator are the first byte of the group
+// of a word.
+const (
+	saidOperator = 0xF0
+	saidEnd      = 0xFF
+)
+
+var saidOperators = ",&/()[]#<>"
+
+// DecodeSaid writes a said spec, without its terminator, with the first word
+// of each group, such as "look<at/tree".
+func (v *Vocabulary) DecodeSaid(b []byte) (string, error) {
+	var s strings.Builder
+	lastWord := false
+	for i := 0; i < len(b); i++ {
+		if c := b[i]; c >= saidOperator {
+			if int(c-saidOperator) >= len(saidOperators) {
+				return "", fmt.Errorf("invalid said token %02x", c)
+			}
+			s.WriteByte(saidOperators[c-saidOperator])
+			lastWord = false
+			continue
+		}
+		if i+1 >= len(b) {
+			return "", errors.New("truncated said spec")
+		}
+		group := uint16(b[i])<<8 | uint16(b[i+1])
+		i++
+		word, ok := v.groups[group]
+		if !ok {
+			return "", fmt.Errorf("no word in group %03x", group)
+		}
+		if lastWord {
+			s.WriteByte(' ')
+		}
+		s.WriteString(word)
+		lastWord = true
+	}
+	return s.String(), nil
+}
+
+// EncodeSaid packs a said spec, such as "look<at/tree", with its terminator.
+// Each word must be in the vocabulary.
+func (v *Vocabulary) EncodeSaid(s string) ([]byte, error) {
+	var b []byte
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ':
+			i++
+		case strings.IndexByte(saidOperators, c) >= 0:
+			b = append(b, saidOperator+byte(strings.IndexByte(saidOperators, c)))
+			i++
+		default:
+			end := i
+			for end < len(s) && s[end] != ' ' && strings.IndexByte(saidOperators, s[end]) < 0 {
+				end++
+			}
+			group, ok := v.words[s[i:end]]
+			if !ok {
+				return nil, fmt.Errorf("%q is not in the vocabulary", s[i:end])
+			}
+			if group>>8 >= saidOperator {
+				return nil, fmt.Errorf("group %03x of %q cannot be said", group, s[i:end])
+			}
+			b = append(b, uint8(group>>8), uint8(group))
+			i = end
+		}
+	}
+	return append(b, saidEnd), nil
+}
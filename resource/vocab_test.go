@@ -0,0 +1,54 @@
+package resource
+
+import (
+	"bytes"
+	"testing"
+)
+
+// vocabWord packs a word of a vocabulary, sharing no characters with the
+// word before it.
+func vocabWord(text string, class, group uint16) []byte {
+	b := append([]byte{0}, text...)
+	b[len(b)-1] |= 0x80
+	return append(b, uint8(class>>4), uint8(class&0xF)<<4|uint8(group>>8), uint8(group))
+}
+
+func TestVocabulary(t *testing.T) {
+	b := make([]byte, 26*2)
+	b = append(b, vocabWord("at", 0x001, 0x010)...)
+	b = append(b, vocabWord("look", 0x800, 0x020)...)
+	// shares "loo" with look
+	b = append(b, 0x03, 'k', 'e'|0x80, 0x80, 0x00, 0x20)
+	b = append(b, vocabWord("rock", 0x010, 0x1FF)...)
+
+	v, err := NewVocabulary(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Words) != 4 || v.Words[2] != (Word{Text: "looke", Class: 0x800, Group: 0x020}) {
+		t.Fatalf("got %+v", v.Words)
+	}
+
+	spec, err := v.EncodeSaid("looke<at/rock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0x00, 0x20, 0xF8, 0x00, 0x10, 0xF2, 0x01, 0xFF, 0xFF}
+	if !bytes.Equal(spec, expected) {
+		t.Errorf("got %x", spec)
+	}
+	// synonyms are written with the first word of their group
+	if s, err := v.DecodeSaid(spec[:len(spec)-1]); err != nil || s != "look<at/rock" {
+		t.Errorf("got %q, %v", s, err)
+	}
+	if _, err := v.EncodeSaid("look/tree"); err == nil {
+		t.Error("encoded a word that is not in the vocabulary")
+	}
+
+	// the group of rock ends in the same byte as the terminator
+	block := ScriptBlock{Type: ScriptBlockSaid, Data: append(spec, 0x00, 0x20, 0xFF)}
+	saids := block.Saids()
+	if len(saids) != 2 || !bytes.Equal(saids[0].Data, spec[:len(spec)-1]) || saids[1].Offset != 4+len(spec) {
+		t.Errorf("got %+v", saids)
+	}
+}
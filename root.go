@@ -90,6 +90,8 @@ func (root *Root) LoadMapping() error {
 			root.Mapping = append(root.Mapping, resource.TextMapping{Mapping: mapping})
 		case resource.TypeCursor:
 			root.Mapping = append(root.Mapping, resource.CursorMapping{Mapping: mapping})
+		case resource.TypeMemory:
+			root.Mapping = append(root.Mapping, resource.MemoryMapping{Mapping: mapping})
 		case resource.TypeFont:
 			root.Mapping = append(root.Mapping, resource.FontMapping{Mapping: mapping})
 		default:
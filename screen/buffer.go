@@ -10,3 +10,24 @@ type Buffer interface {
 	Pattern(cx, cy, size int, isRect bool, isSolid bool, seed uint8, color uint8)
 	Fill(cx, cy int, legalColor uint8, color uint8)
 }
+
+// Plotter is a Buffer that can draw a single pixel. The buffers of the
+// Scalers of this package are all Plotters.
+type Plotter interface {
+	Buffer
+	Plot(x, y int, color uint8)
+}
+
+// Plot draws a single pixel of a picture onto b. Pixels outside of the
+// picture are skipped. Buffers that are not a Plotter draw it as a line of
+// one pixel.
+func Plot(b Buffer, x, y int, color uint8) {
+	if p, ok := b.(Plotter); ok {
+		p.Plot(x, y, color)
+		return
+	}
+	if x < 0 || x > 319 || y < 0 || y > 189 {
+		return
+	}
+	b.Line(x, y, x, y, color)
+}
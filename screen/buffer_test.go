@@ -0,0 +1,31 @@
+package screen
+
+import (
+	"image"
+	"testing"
+)
+
+// lineBuffer is a Buffer that is not a Plotter.
+type lineBuffer struct{ Buffer }
+
+func TestPlotWithoutPlotter(t *testing.T) {
+	pic := Scaler1x1{}.NewPic(image.Rect(0, 0, 320, 190))
+	b := lineBuffer{pic.Visual()}
+	b.Clear(0xFF)
+
+	Plot(b, 5, 7, 0x11)
+	Plot(b, -1, 7, 0x11)
+	Plot(b, 320, 7, 0x11)
+	Plot(b, 5, 190, 0x11)
+
+	img := b.Image().(*image.Paletted)
+	for i, c := range img.Pix {
+		expected := uint8(15)
+		if i == img.PixOffset(5, 7) {
+			expected = 1
+		}
+		if c != expected {
+			t.Fatalf("%d,%d: got %d, expected %d", i%img.Stride, i/img.Stride, c, expected)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package screen
+
+import (
+	"container/heap"
+	"image"
+)
+
+// ControlMap is a copy of a control layer, with the control color of each
+// pixel. Sets of control colors are bit masks, with bit n set for color n.
+type ControlMap struct {
+	Rect image.Rectangle
+	Pix  []uint8
+}
+
+// NewControlMap copies the control colors of a buffer. Buffers that are not
+// paletted are matched against the EGA palette.
+func NewControlMap(b Buffer) *ControlMap {
+	img := b.Image()
+	r := img.Bounds()
+	m := &ControlMap{Rect: r, Pix: make([]uint8, r.Dx()*r.Dy())}
+
+	paletted, isPaletted := img.(interface{ ColorIndexAt(x, y int) uint8 })
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			var c uint8
+			if isPaletted {
+				c = paletted.ColorIndexAt(x, y)
+			} else {
+				c = uint8(DefaultPalettes.EGA.Index(img.At(x, y)))
+			}
+			m.Pix[m.offset(x, y)] = c & 0xF
+		}
+	}
+	return m
+}
+
+func (m *ControlMap) offset(x, y int) int {
+	return (y-m.Rect.Min.Y)*m.Rect.Dx() + (x - m.Rect.Min.X)
+}
+
+// At returns the control color at x, y. Points outside of the map are 0.
+func (m *ControlMap) At(x, y int) uint8 {
+	if !(image.Point{X: x, Y: y}).In(m.Rect) {
+		return 0
+	}
+	return m.Pix[m.offset(x, y)]
+}
+
+// Colors returns the set of control colors within r.
+func (m *ControlMap) Colors(r image.Rectangle) uint16 {
+	var mask uint16
+	r = r.Intersect(m.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			mask |= 1 << m.Pix[m.offset(x, y)]
+		}
+	}
+	return mask
+}
+
+// Region is an area of a single control color, where each pixel touches
+// another above, below, or to the side.
+type Region struct {
+	Color  uint8
+	Area   int
+	Bounds image.Rectangle
+}
+
+// Regions is the control layer split into regions.
+type Regions struct {
+	Rect image.Rectangle
+	// Labels holds the index in Regions of the region of each pixel.
+	Labels  []int
+	Regions []Region
+}
+
+// At returns the index of the region at x, y, or -1 for points outside of
+// the map.
+func (r *Regions) At(x, y int) int {
+	if !(image.Point{X: x, Y: y}).In(r.Rect) {
+		return -1
+	}
+	return r.Labels[(y-r.Rect.Min.Y)*r.Rect.Dx()+(x-r.Rect.Min.X)]
+}
+
+// Regions labels each region of the control layer. Regions are numbered in
+// the order that their top-left pixel is found, scanning from the top.
+func (m *ControlMap) Regions() *Regions {
+	regions := &Regions{Rect: m.Rect, Labels: make([]int, len(m.Pix))}
+	for i := range regions.Labels {
+		regions.Labels[i] = -1
+	}
+
+	var stack []image.Point
+	for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+		for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+			if regions.Labels[m.offset(x, y)] >= 0 {
+				continue
+			}
+
+			label := len(regions.Regions)
+			region := Region{Color: m.Pix[m.offset(x, y)]}
+			regions.Labels[m.offset(x, y)] = label
+			stack = append(stack[:0], image.Point{X: x, Y: y})
+			for len(stack) > 0 {
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				region.Area++
+				region.Bounds = region.Bounds.Union(image.Rect(p.X, p.Y, p.X+1, p.Y+1))
+
+				for _, d := range neighbors4 {
+					n := p.Add(d)
+					if !n.In(m.Rect) {
+						continue
+					}
+					i := m.offset(n.X, n.Y)
+					if regions.Labels[i] < 0 && m.Pix[i] == region.Color {
+						regions.Labels[i] = label
+						stack = append(stack, n)
+					}
+				}
+			}
+			regions.Regions = append(regions.Regions, region)
+		}
+	}
+	return regions
+}
+
+var neighbors4 = []image.Point{{0, -1}, {-1, 0}, {1, 0}, {0, 1}}
+
+var neighbors8 = []image.Point{
+	{-1, -1}, {0, -1}, {1, -1},
+	{-1, 0}, {1, 0},
+	{-1, 1}, {0, 1}, {1, 1},
+}
+
+// Walkable is the set of pixels that an actor can stand on.
+type Walkable struct {
+	Rect image.Rectangle
+	Pix  []bool
+}
+
+// Walkable returns the pixels whose control color is not in blocking.
+func (m *ControlMap) Walkable(blocking uint16) *Walkable {
+	w := &Walkable{Rect: m.Rect, Pix: make([]bool, len(m.Pix))}
+	for i, c := range m.Pix {
+		w.Pix[i] = blocking&(1<<c) == 0
+	}
+	return w
+}
+
+func (w *Walkable) offset(x, y int) int {
+	return (y-w.Rect.Min.Y)*w.Rect.Dx() + (x - w.Rect.Min.X)
+}
+
+// At reports whether x, y can be walked on.
+func (w *Walkable) At(x, y int) bool {
+	return (image.Point{X: x, Y: y}).In(w.Rect) && w.Pix[w.offset(x, y)]
+}
+
+// Area returns the number of pixels that can be walked on.
+func (w *Walkable) Area() int {
+	area := 0
+	for _, ok := range w.Pix {
+		if ok {
+			area++
+		}
+	}
+	return area
+}
+
+// step reports whether p can be walked to from p - d. A diagonal step cannot
+// squeeze between two pixels that cannot be walked on, as a line on the
+// control layer is only joined at its corners.
+func (w *Walkable) step(p, d image.Point) bool {
+	if !w.At(p.X, p.Y) {
+		return false
+	}
+	if d.X != 0 && d.Y != 0 {
+		return w.At(p.X-d.X, p.Y) || w.At(p.X, p.Y-d.Y)
+	}
+	return true
+}
+
+// Reachable returns the pixels that can be reached by walking from p, in any
+// of the eight directions. It is empty when p cannot be walked on.
+func (w *Walkable) Reachable(p image.Point) *Walkable {
+	r := &Walkable{Rect: w.Rect, Pix: make([]bool, len(w.Pix))}
+	if !w.At(p.X, p.Y) {
+		return r
+	}
+
+	r.Pix[w.offset(p.X, p.Y)] = true
+	stack := []image.Point{p}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, d := range neighbors8 {
+			n := p.Add(d)
+			if w.step(n, d) && !r.Pix[w.offset(n.X, n.Y)] {
+				r.Pix[w.offset(n.X, n.Y)] = true
+				stack = append(stack, n)
+			}
+		}
+	}
+	return r
+}
+
+// Path finds a shortest path from one point to another with A*, moving in
+// any of the eight directions. The path includes both ends. It is nil when
+// there is no path.
+func (w *Walkable) Path(from, to image.Point) []image.Point {
+	if !w.At(from.X, from.Y) || !w.At(to.X, to.Y) {
+		return nil
+	}
+
+	const unvisited = -1
+	var (
+		cost   = make([]int, len(w.Pix))
+		parent = make([]int, len(w.Pix))
+		open   = &pathQueue{}
+		start  = w.offset(from.X, from.Y)
+		goal   = w.offset(to.X, to.Y)
+	)
+	for i := range cost {
+		cost[i] = unvisited
+	}
+	cost[start], parent[start] = 0, start
+	heap.Push(open, pathNode{p: from, i: start, estimate: octile(from, to)})
+
+	for open.Len() > 0 {
+		node := heap.Pop(open).(pathNode)
+		if node.i == goal {
+			break
+		}
+		if node.estimate-octile(node.p, to) > cost[node.i] {
+			// a shorter path to this pixel was already found
+			continue
+		}
+
+		for _, d := range neighbors8 {
+			n := node.p.Add(d)
+			if !w.step(n, d) {
+				continue
+			}
+			step := 10
+			if d.X != 0 && d.Y != 0 {
+				step = 14
+			}
+			i := w.offset(n.X, n.Y)
+			if c := cost[node.i] + step; cost[i] == unvisited || c < cost[i] {
+				cost[i], parent[i] = c, node.i
+				heap.Push(open, pathNode{p: n, i: i, estimate: c + octile(n, to)})
+			}
+		}
+	}
+
+	if cost[goal] == unvisited {
+		return nil
+	}
+	var path []image.Point
+	for i := goal; ; i = parent[i] {
+		path = append(path, image.Point{
+			X: w.Rect.Min.X + i%w.Rect.Dx(),
+			Y: w.Rect.Min.Y + i/w.Rect.Dx(),
+		})
+		if i == start {
+			break
+		}
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// octile estimates the cost of the path between two points, where a straight
+// step costs 10 and a diagonal step costs 14.
+func octile(a, b image.Point) int {
+	dx, dy := absInt(a.X-b.X), absInt(a.Y-b.Y)
+	if dx < dy {
+		dx, dy = dy, dx
+	}
+	return 10*(dx-dy) + 14*dy
+}
+
+type pathNode struct {
+	p        image.Point
+	i        int
+	estimate int
+}
+
+type pathQueue []pathNode
+
+func (q pathQueue) Len() int            { return len(q) }
+func (q pathQueue) Less(i, j int) bool  { return q[i].estimate < q[j].estimate }
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathNode)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
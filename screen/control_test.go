@@ -0,0 +1,91 @@
+package screen
+
+import (
+	"image"
+	"testing"
+)
+
+func TestControlMap(t *testing.T) {
+	pic := Scaler1x1{}.NewPic(image.Rect(0, 0, 320, 190))
+	control := pic.Control()
+	control.Clear(0)
+	// a barrier across the picture, with a gap at the bottom
+	control.Line(160, 0, 160, 179, 4)
+	// a closed box, that nothing can walk into
+	control.Line(20, 20, 40, 20, 4)
+	control.Line(40, 20, 40, 40, 4)
+	control.Line(40, 40, 20, 40, 4)
+	control.Line(20, 40, 20, 20, 4)
+	control.Fill(30, 30, 0, 2)
+
+	m := NewControlMap(control)
+	if got := m.Colors(image.Rect(150, 10, 170, 11)); got != 1<<0|1<<4 {
+		t.Errorf("got colors %016b", got)
+	}
+
+	regions := m.Regions()
+	if len(regions.Regions) != 4 {
+		t.Fatalf("got %d regions, expected 4", len(regions.Regions))
+	}
+	if r := regions.Regions[regions.At(30, 30)]; r.Color != 2 || r.Area != 19*19 || r.Bounds != image.Rect(21, 21, 40, 40) {
+		t.Errorf("got water region %+v", r)
+	}
+	if regions.At(10, 10) != regions.At(300, 10) {
+		t.Error("both sides of the barrier should be one region")
+	}
+
+	walkable := m.Walkable(1<<4 | 1<<2)
+	reachable := walkable.Reachable(image.Point{X: 10, Y: 10})
+	if !reachable.At(300, 10) || reachable.At(30, 30) {
+		t.Error("unexpected reachable area")
+	}
+	if reachable.Area() != walkable.Area() {
+		t.Errorf("got reachable area %d, expected %d", reachable.Area(), walkable.Area())
+	}
+
+	path := walkable.Path(image.Point{X: 150, Y: 10}, image.Point{X: 170, Y: 10})
+	if len(path) == 0 {
+		t.Fatal("no path found")
+	}
+	if path[0] != (image.Point{X: 150, Y: 10}) || path[len(path)-1] != (image.Point{X: 170, Y: 10}) {
+		t.Errorf("path runs from %v to %v", path[0], path[len(path)-1])
+	}
+	for i, p := range path {
+		if !walkable.At(p.X, p.Y) {
+			t.Fatalf("path crosses %v", p)
+		}
+		if i > 0 {
+			if d := p.Sub(path[i-1]); absInt(d.X) > 1 || absInt(d.Y) > 1 {
+				t.Fatalf("path jumps from %v to %v", path[i-1], p)
+			}
+		}
+	}
+	// down the barrier, through the gap at y=180, and back up
+	if len(path) != 1+170+170 {
+		t.Errorf("got path of %d points", len(path))
+	}
+
+	if path := walkable.Path(image.Point{X: 10, Y: 10}, image.Point{X: 30, Y: 30}); path != nil {
+		t.Errorf("found a path into the box: %v", path)
+	}
+}
+
+func TestWalkableDiagonalBarrier(t *testing.T) {
+	pic := Scaler1x1{}.NewPic(image.Rect(0, 0, 320, 190))
+	control := pic.Control()
+	control.Clear(0)
+	// only joined at the corners of its pixels
+	control.Line(0, 0, 189, 189, 0x44)
+
+	w := NewControlMap(control).Walkable(1 << 4)
+	from, to := image.Point{X: 100, Y: 10}, image.Point{X: 10, Y: 100}
+	if path := w.Path(from, to); path != nil {
+		t.Errorf("got a path of %d points through the barrier", len(path))
+	}
+	if w.Reachable(from).At(to.X, to.Y) {
+		t.Error("reached across the barrier")
+	}
+	if !w.Reachable(from).At(300, 10) {
+		t.Error("could not reach the same side of the barrier")
+	}
+}
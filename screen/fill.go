@@ -0,0 +1,78 @@
+package screen
+
+// spanFill is a scanline flood fill. It fills whole runs of pixels at once,
+// and only keeps one seed for each run that it has yet to fill, so the
+// memory that it needs does not grow with the area of the fill. Its buffers
+// are reused between fills.
+type spanFill struct {
+	width, height int
+	// seen marks the pixels that have been filled, as a pixel that is filled
+	// with a dithered color may still be legal.
+	seen  []bool
+	seeds []point
+}
+
+func newSpanFill(width, height int) *spanFill {
+	return &spanFill{
+		width:  width,
+		height: height,
+		seen:   make([]bool, width*height),
+	}
+}
+
+// fill calls plot once for each pixel that is legal, and is connected to x, y
+// by legal pixels above, below, or to the side.
+func (f *spanFill) fill(x, y int, legal func(x, y int) bool, plot func(x, y int)) {
+	if x < 0 || x >= f.width || y < 0 || y >= f.height {
+		return
+	}
+	for i := range f.seen {
+		f.seen[i] = false
+	}
+
+	open := func(x, y int) bool {
+		return !f.seen[y*f.width+x] && legal(x, y)
+	}
+
+	seeds := append(f.seeds[:0], point{x, y})
+	for len(seeds) > 0 {
+		p := seeds[len(seeds)-1]
+		seeds = seeds[:len(seeds)-1]
+		if !open(p.x, p.y) {
+			continue
+		}
+
+		left, right := p.x, p.x
+		for left > 0 && open(left-1, p.y) {
+			left--
+		}
+		for right < f.width-1 && open(right+1, p.y) {
+			right++
+		}
+
+		row := p.y * f.width
+		for x := left; x <= right; x++ {
+			f.seen[row+x] = true
+			plot(x, p.y)
+		}
+
+		// one seed for each run of open pixels above and below
+		for _, ny := range [2]int{p.y - 1, p.y + 1} {
+			if ny < 0 || ny >= f.height {
+				continue
+			}
+			inRun := false
+			for x := left; x <= right; x++ {
+				if !open(x, ny) {
+					inRun = false
+					continue
+				}
+				if !inRun {
+					seeds = append(seeds, point{x, ny})
+					inRun = true
+				}
+			}
+		}
+	}
+	f.seeds = seeds
+}
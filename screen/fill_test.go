@@ -0,0 +1,278 @@
+package screen
+
+import (
+	"bytes"
+	"image"
+	"math/rand"
+	"testing"
+)
+
+// drawFillTestPic draws random lines and brushes, that leave many small
+// areas for a fill to find its way through.
+func drawFillTestPic(pic Pic, seed int64) {
+	r := rand.New(rand.NewSource(seed))
+	v := pic.Visual()
+	v.Clear(0xFF)
+	for i := 0; i < 60; i++ {
+		v.Line(r.Intn(320), r.Intn(190), r.Intn(320), r.Intn(190), 0x00)
+	}
+	for i := 0; i < 20; i++ {
+		v.Pattern(r.Intn(320), r.Intn(190), r.Intn(8), r.Intn(2) == 0, false, uint8(r.Intn(120)), 0x44)
+	}
+}
+
+// queueFill1x1 is the point-queue fill that buffer1x1 used before spanFill.
+// It is kept as the reference for the span fill.
+func queueFill1x1(buf *buffer1x1, cx, cy int, legalColor uint8, color uint8) {
+	isLegal := func(p point, legalColor uint8) bool {
+		idx := p.y*buf.Stride + p.x
+		return buf.Pix[idx] == legalColor
+	}
+
+	var (
+		p      point
+		stride = buf.Stride
+		stack  []point
+	)
+
+	// initial
+	stack = append(stack, point{cx, cy})
+
+	for len(stack) > 0 {
+		p, stack = stack[0], stack[1:]
+
+		var (
+			x, y = p.x, p.y
+			i    = y*stride + x
+		)
+
+		if !isLegal(p, legalColor) {
+			continue
+		}
+
+		buf.Pix[i] = buf.DitherAt(x, y, color)
+
+		if down := (point{x, y + 1}); down.y < 190 {
+			if isLegal(down, legalColor) {
+				stack = append(stack, down)
+			}
+		}
+
+		if up := (point{x, y - 1}); up.y >= 0 {
+			if isLegal(up, legalColor) {
+				stack = append(stack, up)
+			}
+		}
+
+		// flood right
+		for dx := x + 1; dx < 320; dx++ {
+			var i = y*stride + dx
+			if buf.Pix[i] != legalColor {
+				break
+			}
+
+			buf.Pix[i] = buf.DitherAt(dx, y, color)
+			if down := (point{dx, y + 1}); down.y < 190 {
+				if isLegal(down, legalColor) {
+					stack = append(stack, down)
+				}
+			}
+			if up := (point{dx, y - 1}); up.y >= 0 {
+				if isLegal(up, legalColor) {
+					stack = append(stack, up)
+				}
+			}
+		}
+
+		// flood left
+		for dx := x - 1; dx >= 0; dx-- {
+			var i = y*stride + dx
+			if buf.Pix[i] != legalColor {
+				break
+			}
+
+			buf.Pix[i] = buf.DitherAt(dx, y, color)
+			if down := (point{dx, y + 1}); down.y < 190 {
+				if isLegal(down, legalColor) {
+					stack = append(stack, down)
+				}
+			}
+			if up := (point{dx, y - 1}); up.y >= 0 {
+				if isLegal(up, legalColor) {
+					stack = append(stack, up)
+				}
+			}
+		}
+	}
+}
+
+// queueFillNxM is the point-queue fill that bufferNxM used before spanFill.
+// It is kept as the reference for the span fill.
+func queueFillNxM(b bufferNxM, cx, cy int, legalColor uint8, color uint8) {
+	isLegal := func(p point, legalColor uint8) bool {
+		return b.fillBuffer[p.y*b.bounds.Dx()+p.x] == legalColor
+	}
+
+	var (
+		p     point
+		stack []point
+	)
+
+	// initial
+	stack = append(stack, point{cx, cy})
+
+	for len(stack) > 0 {
+		p, stack = stack[0], stack[1:]
+
+		var (
+			x, y = p.x, p.y
+		)
+
+		if !isLegal(p, legalColor) {
+			continue
+		}
+
+		b.plot(x, y, color)
+
+		if down := (point{x, y + 1}); down.y < 190 {
+			if isLegal(down, legalColor) {
+				stack = append(stack, down)
+			}
+		}
+
+		if up := (point{x, y - 1}); up.y >= 0 {
+			if isLegal(up, legalColor) {
+				stack = append(stack, up)
+			}
+		}
+
+		// flood right
+		for dx := x + 1; dx < 320; dx++ {
+			right := point{dx, y}
+			if !isLegal(right, legalColor) {
+				break
+			}
+
+			b.plot(right.x, right.y, color)
+			if down := (point{dx, y + 1}); down.y < 190 {
+				if isLegal(down, legalColor) {
+					stack = append(stack, down)
+				}
+			}
+			if up := (point{dx, y - 1}); up.y >= 0 {
+				if isLegal(up, legalColor) {
+					stack = append(stack, up)
+				}
+			}
+		}
+
+		// flood left
+		for dx := x - 1; dx >= 0; dx-- {
+			left := point{dx, y}
+			if !isLegal(left, legalColor) {
+				break
+			}
+
+			b.plot(left.x, left.y, color)
+			if down := (point{dx, y + 1}); down.y < 190 {
+				if isLegal(down, legalColor) {
+					stack = append(stack, down)
+				}
+			}
+			if up := (point{dx, y - 1}); up.y >= 0 {
+				if isLegal(up, legalColor) {
+					stack = append(stack, up)
+				}
+			}
+		}
+	}
+}
+
+// referenceFill fills the visual layer of pic with the point-queue fill.
+// That fill never finishes when a dither of color contains legalColor, as
+// the pixels that it fills stay legal. Those fills find their area with a
+// solid color first, and then draw color over that area.
+func referenceFill(pic Pic, cx, cy int, legalColor, color uint8) {
+	fill := color
+	if color&0xF == legalColor || color>>4 == legalColor {
+		solid := (legalColor + 1) & 0xF
+		fill = solid<<4 | solid
+	}
+
+	switch b := pic.Visual().(type) {
+	case *buffer1x1:
+		before := append([]uint8(nil), b.Pix...)
+		queueFill1x1(b, cx, cy, legalColor, fill)
+		for i := range b.Pix {
+			if b.Pix[i] != before[i] {
+				b.Pix[i] = b.DitherAt(i%b.Stride, i/b.Stride, color)
+			}
+		}
+	case *bufferNxM:
+		before := append([]uint8(nil), b.fillBuffer...)
+		queueFillNxM(*b, cx, cy, legalColor, fill)
+		for i := range b.fillBuffer {
+			if b.fillBuffer[i] != before[i] {
+				b.plot(i%b.bounds.Dx(), i/b.bounds.Dx(), color)
+			}
+		}
+	default:
+		panic("unexpected buffer")
+	}
+}
+
+func TestSpanFill(t *testing.T) {
+	bounds := image.Rect(0, 0, 320, 190)
+	scalers := map[string]Scaler{
+		"1x1": Scaler1x1{},
+		"5x6": Scaler5x6{},
+	}
+	// dithers with white in them leave legal pixels behind
+	for name, s := range scalers {
+		for _, color := range []uint8{0x22, 0x9a, 0xf0, 0x0f} {
+			for seed := int64(0); seed < 4; seed++ {
+				actual := s.NewPic(bounds)
+				expected := s.NewPic(bounds)
+				drawFillTestPic(actual, seed)
+				drawFillTestPic(expected, seed)
+
+				// not the source of the picture, whose first points are all
+				// on its lines
+				r := rand.New(rand.NewSource(^seed))
+				for i := 0; i < 10; i++ {
+					x, y := r.Intn(320), r.Intn(190)
+					actual.Visual().Fill(x, y, 0xF, color)
+					referenceFill(expected, x, y, 0xF, color)
+				}
+
+				a := actual.Visual().Image().(*image.Paletted)
+				e := expected.Visual().Image().(*image.Paletted)
+				if !bytes.Equal(a.Pix, e.Pix) {
+					t.Errorf("%s, color %02x, seed %d: fill differs from reference", name, color, seed)
+				}
+			}
+		}
+	}
+}
+
+func TestSpanFillOutside(t *testing.T) {
+	pic := Scaler1x1{}.NewPic(image.Rect(0, 0, 320, 190))
+	pic.Visual().Clear(0xFF)
+	pic.Visual().Fill(-1, 200, 0xF, 0x11)
+	if c := pic.Visual().Image().(*image.Paletted).ColorIndexAt(0, 189); c != 0xF {
+		t.Errorf("got %d", c)
+	}
+}
+
+func benchmarkFill(b *testing.B, s Scaler) {
+	pic := s.NewPic(image.Rect(0, 0, 320, 190))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		drawFillTestPic(pic, 1)
+		pic.Visual().Fill(0, 0, 0xF, 0x22)
+		pic.Visual().Fill(319, 189, 0xF, 0x9a)
+	}
+}
+
+func BenchmarkFill1x1(b *testing.B) { benchmarkFill(b, Scaler1x1{}) }
+func BenchmarkFill5x6(b *testing.B) { benchmarkFill(b, Scaler5x6{}) }
@@ -0,0 +1,58 @@
+package screen
+
+// drawLine calls plot for each pixel of a line, the same way as the
+// interpreter. The ends of the line are moved inside of the picture.
+func drawLine(x1, y1, x2, y2 int, plot func(x, y int)) {
+	var (
+		left   = clampInt(0, 319, x1)
+		top    = clampInt(0, 189, y1)
+		right  = clampInt(0, 319, x2)
+		bottom = clampInt(0, 189, y2)
+	)
+
+	switch {
+	case left == right:
+		swapIf(&top, &bottom, top > bottom)
+		for y := top; y <= bottom; y++ {
+			plot(left, y)
+		}
+	case top == bottom:
+		swapIf(&right, &left, right > left)
+		for x := right; x <= left; x++ {
+			plot(x, top)
+		}
+	default:
+		// bresenham
+		dx, dy := right-left, bottom-top
+		stepX, stepY := ((dx>>15)<<1)+1, ((dy>>15)<<1)+1
+
+		dx, dy = absInt(dx)<<1, absInt(dy)<<1
+
+		plot(left, top)
+		plot(right, bottom)
+
+		if dx > dy {
+			fraction := dy - (dx >> 1)
+			for left != right {
+				if fraction >= 0 {
+					top += stepY
+					fraction -= dx
+				}
+				left += stepX
+				fraction += dy
+				plot(left, top)
+			}
+		} else {
+			fraction := dx - (dy >> 1)
+			for top != bottom {
+				if fraction >= 0 {
+					left += stepX
+					fraction -= dy
+				}
+				top += stepY
+				fraction += dx
+				plot(left, top)
+			}
+		}
+	}
+}
@@ -1,46 +1,16 @@
 package screen
 
-/* TODO some images are more sensitive to textured fills, and will render incorrectly if the *exact* random
-   number generator is not used. It looks like they targeted ~25% fill-rate for their textured fills. This
-   doesn't look like its the 100% proper implementation, I'll have to try to hunt down something that is more
-   accurate.
-*/
-
-/* Found at https://github.com/wjp/freesci-archive/blob/master/src/gfx/resource/sci_pic_0.c */
-/* 'Random' fill patterns, provided by Carl Muckenhoupt: */
-var noise = [...]bool{
-	false, false, true, false, false, false, false, false,
-	true, false, false, true, false, true, false, false,
-	false, false, false, false, false, false, true, false,
-	false, false, true, false, false, true, false, false,
-	true, false, false, true, false, false, false, false,
-	true, false, false, false, false, false, true, false,
-	true, false, true, false, false, true, false, false,
-	true, false, true, false, false, false, true, false,
-	true, false, false, false, false, false, true, false,
-	false, false, false, false, true, false, false, true,
-	false, false, false, false, true, false, true, false,
-	false, false, true, false, false, false, true, false,
-	false, false, false, true, false, false, true, false,
-	false, false, false, true, false, false, false, false,
-	false, true, false, false, false, false, true, false,
-	false, false, false, true, false, true, false, false,
-	true, false, false, true, false, false, false, true,
-	false, true, false, false, true, false, true, false,
-	true, false, false, true, false, false, false, true,
-	false, false, false, true, false, false, false, true,
-	false, false, false, false, true, false, false, false,
-	false, false, false, true, false, false, true, false,
-	false, false, true, false, false, true, false, true,
-	false, false, false, true, false, false, false, false,
-	false, false, true, false, false, false, true, false,
-	true, false, true, false, true, false, false, false,
-	false, false, false, true, false, true, false, false,
-	false, false, true, false, false, true, false, false,
-	false, false, false, false, false, false, false, false,
-	false, true, false, true, false, false, false, false,
-	false, false, true, false, false, true, false, false,
-	false, false, false, false, false, true, false,
+// noise is the bit table of textured brushes, as found in the interpreter.
+// Bits are read from the most significant bit of each byte, and the position
+// within the table is a byte, so reading wraps around after 256 bits.
+//
+// Found at https://github.com/wjp/freesci-archive/blob/master/src/gfx/resource/sci_pic_0.c
+// 'Random' fill patterns, provided by Carl Muckenhoupt.
+var noise = [32]uint8{
+	0x20, 0x94, 0x02, 0x24, 0x90, 0x82, 0xa4, 0xa2,
+	0x82, 0x09, 0x0a, 0x22, 0x12, 0x10, 0x42, 0x14,
+	0x91, 0x4a, 0x91, 0x11, 0x08, 0x12, 0x25, 0x10,
+	0x22, 0xa8, 0x14, 0x24, 0x00, 0x50, 0x24, 0x04,
 }
 
 /* Found at https://github.com/wjp/freesci-archive/blob/master/src/gfx/resource/sci_pic_0.c */
@@ -64,3 +34,17 @@ var noiseOffsets = [...]int{
 	// offset of >= 120 is not allowed, when encoded
 	// it overlaps with the OP code range.
 }
+
+// patternNoise walks the bits of the noise table.
+type patternNoise uint8
+
+// newPatternNoise starts at the offset of a texture.
+func newPatternNoise(seed uint8) patternNoise {
+	return patternNoise(noiseOffsets[seed])
+}
+
+func (n *patternNoise) next() bool {
+	bit := noise[*n>>3]&(0x80>>(*n&7)) != 0
+	*n++
+	return bit
+}
@@ -0,0 +1,185 @@
+package screen
+
+import (
+	"image"
+	"image/color"
+
+	clr "github.com/lucasb-eyer/go-colorful"
+)
+
+// PairScaler draws the visual layer with one pixel for each pixel of the
+// picture, that holds the pair of EGA colors drawn there, rather than a
+// dithered color. The palette of the image is the PairPalette of the
+// Ditherer. The images are what OptimizeDitherer learns from.
+type PairScaler struct {
+	*Ditherer
+}
+
+func (s PairScaler) NewPic(bounds image.Rectangle) Pic {
+	return newPairPic(bounds, s.Ditherer.PairPalette())
+}
+
+// newPairPic draws the pairs of colors as themselves, so that the visual
+// layer holds the code of each pair.
+func newPairPic(bounds image.Rectangle, pairs color.Palette) *picLayers {
+	identity := make(ColorMapping, 256)
+	for i := range pairs {
+		identity[uint8(i)] = struct{ c1, c2 uint8 }{uint8(i), uint8(i)}
+	}
+	return ScalerNxM{
+		Ditherer: &Ditherer{Palette: pairs, ColorMapping: identity, DitherFn: noDither},
+		X:        1,
+		Y:        1,
+	}.NewPic(bounds).(*picLayers)
+}
+
+// OptimizeDitherer searches for the colors that best replace the dithered
+// pairs of pal, for pictures that were drawn with a PairScaler.
+//
+// The reference for each picture is its dithered render with pal, blurred
+// with a 2x2 box filter, which is how the dither looks from a distance. Each
+// pair is given two colors, one for the pixels where each of its EGA colors
+// would be, which are chosen to minimize the total CIEDE2000 difference from
+// the reference. With the 16 colors of pal, this fills a palette of 256.
+// Pairs that do not appear in any picture are mixed 50/50.
+func OptimizeDitherer(pal color.Palette, pics ...*image.Paletted) *Ditherer {
+	if len(pal) < 16 {
+		pal = DefaultPalettes.EGA
+	}
+	pal = pal[:16]
+
+	// the reference colors for each EGA color of each pair, with the number
+	// of pixels that they appear at
+	targets := make(map[[2]uint8]map[color.RGBA]int)
+	for _, pic := range pics {
+		collectTargets(pic, pal, targets)
+	}
+
+	newPal := make(color.Palette, 16, 256)
+	copy(newPal, pal)
+	mapping := ColorMapping{}
+	for a := uint8(0); a < 0x10; a++ {
+		for b := a + 1; b < 0x10; b++ {
+			fallback := linearMix(pal[a], pal[b])
+			idxA := uint8(len(newPal))
+			newPal = append(newPal, optimizeColor(targets[[2]uint8{a, b}], fallback))
+			idxB := uint8(len(newPal))
+			newPal = append(newPal, optimizeColor(targets[[2]uint8{b, a}], fallback))
+			mapping[b<<4|a] = struct{ c1, c2 uint8 }{idxA, idxB}
+			mapping[a<<4|b] = struct{ c1, c2 uint8 }{idxB, idxA}
+		}
+	}
+
+	return &Ditherer{
+		Palette:      newPal,
+		ColorMapping: mapping,
+	}
+}
+
+// collectTargets adds the reference color of each pixel of a picture to the
+// targets of its pair, keyed by the EGA color that is dithered at the pixel
+// and the other color of the pair.
+func collectTargets(pic *image.Paletted, pal color.Palette, targets map[[2]uint8]map[color.RGBA]int) {
+	r := pic.Rect
+	linear := make([][3]float64, r.Dx()*r.Dy())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			code := pic.ColorIndexAt(x, y)
+			c, _ := clr.MakeColor(pal[dither5050(x, y, code&0xF, code>>4)])
+			lr, lg, lb := c.LinearRgb()
+			linear[(y-r.Min.Y)*r.Dx()+(x-r.Min.X)] = [3]float64{lr, lg, lb}
+		}
+	}
+
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			code := pic.ColorIndexAt(r.Min.X+x, r.Min.Y+y)
+			c1, c2 := code&0xF, code>>4
+			if c1 == c2 {
+				continue
+			}
+
+			// the 2x2 box, moved inside of the picture at the edges
+			bx, by := x, y
+			if bx+1 >= r.Dx() {
+				bx = r.Dx() - 2
+			}
+			if by+1 >= r.Dy() {
+				by = r.Dy() - 2
+			}
+			var sum [3]float64
+			for _, p := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				px, py := clampInt(0, r.Dx()-1, bx+p[0]), clampInt(0, r.Dy()-1, by+p[1])
+				l := linear[py*r.Dx()+px]
+				sum[0], sum[1], sum[2] = sum[0]+l[0], sum[1]+l[1], sum[2]+l[2]
+			}
+			ref := clr.LinearRgb(sum[0]/4, sum[1]/4, sum[2]/4).Clamped()
+			rr, rg, rb := ref.RGB255()
+
+			shown, other := c1, c2
+			if dither5050(r.Min.X+x, r.Min.Y+y, c1, c2) != c1 {
+				shown, other = c2, c1
+			}
+			key := [2]uint8{shown, other}
+			if targets[key] == nil {
+				targets[key] = make(map[color.RGBA]int)
+			}
+			targets[key][color.RGBA{R: rr, G: rg, B: rb, A: 0xFF}]++
+		}
+	}
+}
+
+// optimizeColor finds the color with the least total CIEDE2000 difference
+// from the targets, by a pattern search in Lab space from their mean.
+func optimizeColor(targets map[color.RGBA]int, fallback color.Color) color.Color {
+	if len(targets) == 0 {
+		return fallback
+	}
+
+	type target struct {
+		clr.Color
+		weight float64
+	}
+	var (
+		ts    []target
+		mean  [3]float64
+		total float64
+	)
+	for c, n := range targets {
+		t, _ := clr.MakeColor(c)
+		l, a, b := t.Lab()
+		w := float64(n)
+		mean[0], mean[1], mean[2] = mean[0]+l*w, mean[1]+a*w, mean[2]+b*w
+		total += w
+		ts = append(ts, target{t, w})
+	}
+
+	cost := func(lab [3]float64) float64 {
+		c := clr.Lab(lab[0], lab[1], lab[2]).Clamped()
+		sum := 0.0
+		for _, t := range ts {
+			sum += c.DistanceCIEDE2000(t.Color) * t.weight
+		}
+		return sum
+	}
+
+	best := [3]float64{mean[0] / total, mean[1] / total, mean[2] / total}
+	bestCost := cost(best)
+	for step := 0.05; step > 0.0005; step /= 2 {
+		for improved := true; improved; {
+			improved = false
+			for axis := 0; axis < 3; axis++ {
+				for _, dir := range []float64{-1, 1} {
+					candidate := best
+					candidate[axis] += dir * step
+					if c := cost(candidate); c < bestCost {
+						best, bestCost, improved = candidate, c, true
+					}
+				}
+			}
+		}
+	}
+
+	r, g, b := clr.Lab(best[0], best[1], best[2]).Clamped().RGB255()
+	return color.RGBA{R: r, G: g, B: b, A: 0xFF}
+}
@@ -0,0 +1,45 @@
+package screen
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	clr "github.com/lucasb-eyer/go-colorful"
+)
+
+func TestOptimizeDitherer(t *testing.T) {
+	pic := PairScaler{}.NewPic(image.Rect(0, 0, 320, 190))
+	pic.Visual().Clear(0xFF)
+	pic.Visual().Pattern(100, 100, 7, true, true, 0, 0x14)
+
+	pairs := pic.Visual().Image().(*image.Paletted)
+	if c := pairs.ColorIndexAt(100, 100); c != 0x14 {
+		t.Fatalf("got pair %02x", c)
+	}
+
+	d := OptimizeDitherer(nil, pairs)
+	if len(d.Palette) != 256 {
+		t.Fatalf("got %d colors", len(d.Palette))
+	}
+	if c1, c2 := d.GetMapping(0xFF); c1 != 0xF || c2 != 0xF {
+		t.Errorf("got solid mapping %d, %d", c1, c2)
+	}
+
+	// within the square, the dither averages to the 50/50 mix
+	mix, _ := clr.MakeColor(linearMix(DefaultPalettes.EGA[1], DefaultPalettes.EGA[4]))
+	c1, c2 := d.GetMapping(0x14)
+	for _, i := range []uint8{c1, c2} {
+		got, _ := clr.MakeColor(d.Palette[i])
+		if dist := got.DistanceCIEDE2000(mix); dist > 0.05 {
+			t.Errorf("color %d is %v, %.3f from the mix", i, d.Palette[i], dist)
+		}
+	}
+
+	// pairs that were never drawn are mixed
+	c1, c2 = d.GetMapping(0x23)
+	expected := color.RGBAModel.Convert(linearMix(DefaultPalettes.EGA[2], DefaultPalettes.EGA[3]))
+	if color.RGBAModel.Convert(d.Palette[c1]) != expected || color.RGBAModel.Convert(d.Palette[c2]) != expected {
+		t.Errorf("got %v, %v, expected %v", d.Palette[c1], d.Palette[c2], expected)
+	}
+}
@@ -2,6 +2,8 @@ package screen
 
 import (
 	"image/color"
+
+	clr "github.com/lucasb-eyer/go-colorful"
 )
 
 type Ditherer struct {
@@ -40,6 +42,8 @@ var DefaultPalettes = struct {
 	EGA     color.Palette
 	DB32EGA color.Palette
 	EGACOM  color.Palette
+	CGA     color.Palette
+	Mono    color.Palette
 }{
 	Depth: color.Palette{
 		color.Gray{Y: 0x00},
@@ -117,6 +121,18 @@ var DefaultPalettes = struct {
 		0xe: rgb(230, 196, 57),
 		0xf: rgb(238, 247, 237),
 	},
+	// CGA is the high intensity cyan, magenta and white palette of CGA's
+	// 320x200 mode.
+	CGA: color.Palette{
+		rgb24Color(0x000000),
+		rgb24Color(0x55FFFF),
+		rgb24Color(0xFF55FF),
+		rgb24Color(0xFFFFFF),
+	},
+	Mono: color.Palette{
+		color.Gray{Y: 0x00},
+		color.Gray{Y: 0xFF},
+	},
 }
 
 var DefaultDitherers = struct {
@@ -311,6 +327,57 @@ func NewDitherizer(pal color.Palette, t1 float64, t2 float64) *Ditherer {
 	}
 }
 
+// NewNearestDitherer maps each pair of EGA colors to the pair of colors of
+// pal whose 50/50 dither looks the closest, for displays that have fewer
+// colors than EGA. The lighter of the two colors takes the place of the
+// lighter EGA color, so that the pattern of the dither is kept.
+//
+// The mapping is an approximation, chosen by distance in the Lab color
+// space. It is not the table that the interpreter's drivers use, see
+// NewDriverDitherer for those.
+func NewNearestDitherer(pal color.Palette) *Ditherer {
+	type candidate struct {
+		c1, c2 uint8
+		clr.Color
+	}
+	var candidates []candidate
+	for a := range pal {
+		for b := a; b < len(pal); b++ {
+			mix, _ := clr.MakeColor(linearMix(pal[a], pal[b]))
+			candidates = append(candidates, candidate{uint8(a), uint8(b), mix})
+		}
+	}
+
+	luminance := func(c color.Color) float64 {
+		lab, _ := clr.MakeColor(c)
+		l, _, _ := lab.Lab()
+		return l
+	}
+
+	mapping := ColorMapping{}
+	for i, pair := range (*Ditherer)(nil).PairPalette() {
+		target, _ := clr.MakeColor(pair)
+		best := candidates[0]
+		for _, c := range candidates[1:] {
+			if target.DistanceLab(c.Color) < target.DistanceLab(best.Color) {
+				best = c
+			}
+		}
+
+		ega1, ega2 := DefaultPalettes.EGA[i&0xF], DefaultPalettes.EGA[i>>4]
+		lighter1 := luminance(pal[best.c1]) > luminance(pal[best.c2])
+		if lighter1 != (luminance(ega1) > luminance(ega2)) {
+			best.c1, best.c2 = best.c2, best.c1
+		}
+		mapping[uint8(i)] = struct{ c1, c2 uint8 }{best.c1, best.c2}
+	}
+
+	return &Ditherer{
+		Palette:      pal,
+		ColorMapping: mapping,
+	}
+}
+
 func NewUnditherer(pal color.Palette) *Ditherer {
 	return NewMixDitherer(pal, 0.5)
 }
@@ -382,3 +449,24 @@ func NewAdaptiveDithering(in color.Palette, lower, upper float64) *Ditherer {
 		ColorMapping: mapping,
 	}
 }
+
+// DriverTable is the pair of colors that a display driver dithers each pair
+// of EGA colors with, indexed like the colors of a picture: the low nibble is
+// the EGA color where x+y is even. The first color of an entry is drawn where
+// x+y is even.
+type DriverTable [256][2]uint8
+
+// NewDriverDitherer draws pictures with the colors of pal, as a display
+// driver does with its table. The tables of the SCI0 CGA and Hercules
+// drivers are not part of this package, and can be read with
+// ReadDriverTable.
+func NewDriverDitherer(pal color.Palette, table DriverTable) *Ditherer {
+	mapping := ColorMapping{}
+	for i, pair := range table {
+		mapping[uint8(i)] = struct{ c1, c2 uint8 }{pair[0], pair[1]}
+	}
+	return &Ditherer{
+		Palette:      pal,
+		ColorMapping: mapping,
+	}
+}
@@ -0,0 +1,375 @@
+package screen
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PaletteFormat is a file format for palettes.
+type PaletteFormat uint8
+
+const (
+	// PaletteGPL is a GIMP palette, .gpl.
+	PaletteGPL PaletteFormat = iota
+	// PaletteJASC is a JASC-PAL palette, .pal, as written by Paint Shop Pro.
+	PaletteJASC
+	// PaletteACT is an Adobe color table, .act.
+	PaletteACT
+	// PaletteHex is a list of hex colors, one per line, .hex, as used by
+	// Lospec.
+	PaletteHex
+)
+
+// PaletteFormatOf returns the format of a palette file by its extension.
+func PaletteFormatOf(name string) (PaletteFormat, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gpl":
+		return PaletteGPL, nil
+	case ".pal":
+		return PaletteJASC, nil
+	case ".act":
+		return PaletteACT, nil
+	case ".hex", ".txt":
+		return PaletteHex, nil
+	}
+	return 0, fmt.Errorf("unknown palette format: %s", name)
+}
+
+// ReadPalette reads a palette in the given format.
+func ReadPalette(r io.Reader, format PaletteFormat) (color.Palette, error) {
+	switch format {
+	case PaletteGPL:
+		return readGPL(r)
+	case PaletteJASC:
+		return readJASC(r)
+	case PaletteACT:
+		return readACT(r)
+	case PaletteHex:
+		return readHex(r)
+	}
+	return nil, fmt.Errorf("unknown palette format %d", format)
+}
+
+// WritePalette writes a palette in the given format. The name is only
+// written by formats that have one.
+func WritePalette(w io.Writer, pal color.Palette, format PaletteFormat, name string) error {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case PaletteGPL:
+		fmt.Fprintf(bw, "GIMP Palette\nName: %s\nColumns: 16\n#\n", name)
+		for _, c := range pal {
+			c := color.NRGBAModel.Convert(c).(color.NRGBA)
+			fmt.Fprintf(bw, "%3d %3d %3d\t#%02x%02x%02x\n", c.R, c.G, c.B, c.R, c.G, c.B)
+		}
+	case PaletteJASC:
+		fmt.Fprintf(bw, "JASC-PAL\r\n0100\r\n%d\r\n", len(pal))
+		for _, c := range pal {
+			c := color.NRGBAModel.Convert(c).(color.NRGBA)
+			fmt.Fprintf(bw, "%d %d %d\r\n", c.R, c.G, c.B)
+		}
+	case PaletteACT:
+		if len(pal) > 256 {
+			return errors.New("palette has more than 256 colors")
+		}
+		var table [256 * 3]byte
+		for i, c := range pal {
+			c := color.NRGBAModel.Convert(c).(color.NRGBA)
+			table[i*3], table[i*3+1], table[i*3+2] = c.R, c.G, c.B
+		}
+		bw.Write(table[:])
+		// the number of colors, and no transparent color
+		binary.Write(bw, binary.BigEndian, [2]uint16{uint16(len(pal)), 0xFFFF})
+	case PaletteHex:
+		for _, c := range pal {
+			c := color.NRGBAModel.Convert(c).(color.NRGBA)
+			fmt.Fprintf(bw, "%02x%02x%02x\n", c.R, c.G, c.B)
+		}
+	default:
+		return fmt.Errorf("unknown palette format %d", format)
+	}
+	return bw.Flush()
+}
+
+func rgbComponent(s string) (uint8, error) {
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid color component %q", s)
+	}
+	return uint8(v), nil
+}
+
+func rgbComponents(fields []string) (color.Color, error) {
+	var rgb [3]uint8
+	for i := range rgb {
+		v, err := rgbComponent(fields[i])
+		if err != nil {
+			return nil, err
+		}
+		rgb[i] = v
+	}
+	return color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xFF}, nil
+}
+
+func readGPL(r io.Reader) (color.Palette, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "GIMP Palette" {
+		return nil, errors.New("not a GIMP palette")
+	}
+
+	var pal color.Palette
+	for line := 2; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "", strings.HasPrefix(text, "#"):
+			continue
+		case strings.HasPrefix(text, "Name:"), strings.HasPrefix(text, "Columns:"):
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: invalid color", line)
+		}
+		c, err := rgbComponents(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		pal = append(pal, c)
+	}
+	return pal, scanner.Err()
+}
+
+func readJASC(r io.Reader) (color.Palette, error) {
+	scanner := bufio.NewScanner(r)
+	var header [3]string
+	for i := range header {
+		if !scanner.Scan() {
+			return nil, errors.New("not a JASC-PAL palette")
+		}
+		header[i] = strings.TrimSpace(scanner.Text())
+	}
+	if header[0] != "JASC-PAL" {
+		return nil, errors.New("not a JASC-PAL palette")
+	}
+	count, err := strconv.Atoi(header[2])
+	if err != nil || count < 0 || count > 256 {
+		return nil, fmt.Errorf("invalid color count %q", header[2])
+	}
+
+	pal := make(color.Palette, 0, count)
+	for line := 4; len(pal) < count && scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: invalid color", line)
+		}
+		c, err := rgbComponents(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		pal = append(pal, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(pal) < count {
+		return nil, fmt.Errorf("expected %d colors, found %d", count, len(pal))
+	}
+	return pal, nil
+}
+
+func readACT(r io.Reader) (color.Palette, error) {
+	var table [256 * 3]byte
+	if _, err := io.ReadFull(r, table[:]); err != nil {
+		return nil, fmt.Errorf("invalid color table: %w", err)
+	}
+
+	// the number of colors is optional
+	count := 256
+	var footer [2]uint16
+	if err := binary.Read(r, binary.BigEndian, &footer); err == nil && footer[0] > 0 && footer[0] <= 256 {
+		count = int(footer[0])
+	}
+
+	pal := make(color.Palette, count)
+	for i := range pal {
+		pal[i] = color.RGBA{R: table[i*3], G: table[i*3+1], B: table[i*3+2], A: 0xFF}
+	}
+	return pal, nil
+}
+
+func readHex(r io.Reader) (color.Palette, error) {
+	scanner := bufio.NewScanner(r)
+	var pal color.Palette
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "#")
+		if text == "" || strings.HasPrefix(text, ";") {
+			continue
+		}
+		v, err := strconv.ParseUint(text, 16, 32)
+		if err != nil || len(text) != 6 {
+			return nil, fmt.Errorf("line %d: invalid color %q", line, text)
+		}
+		pal = append(pal, rgb24Color(v))
+	}
+	return pal, scanner.Err()
+}
+
+// DithererKind is one of the ways of building a Ditherer from a palette.
+type DithererKind uint8
+
+const (
+	// DithererPlain dithers the colors of the palette, like the
+	// DefaultDitherers.
+	DithererPlain DithererKind = iota
+	// DithererDitherize uses NewDitherizer, with T1 and T2.
+	DithererDitherize
+	// DithererMix uses NewMixDitherer, with Ratio.
+	DithererMix
+	// DithererAdaptive uses NewAdaptiveDithering, with Lower and Upper.
+	DithererAdaptive
+	// DithererNearest uses NewNearestDitherer, which approximates a driver
+	// for a display with fewer colors.
+	DithererNearest
+)
+
+var dithererKinds = map[string]DithererKind{
+	"plain":     DithererPlain,
+	"ditherize": DithererDitherize,
+	"mix":       DithererMix,
+	"adaptive":  DithererAdaptive,
+	"nearest":   DithererNearest,
+}
+
+// DithererConfig is a kind of Ditherer, and its parameters.
+type DithererConfig struct {
+	Kind         DithererKind
+	T1, T2       float64
+	Ratio        float64
+	Lower, Upper float64
+}
+
+// ParseDithererConfig parses a kind of Ditherer and its parameters, such as
+// "plain", "ditherize:0.1,0.3", "mix:0.5", "adaptive:0.2,0.4" or "nearest".
+func ParseDithererConfig(s string) (DithererConfig, error) {
+	name, args := s, ""
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		name, args = s[:i], s[i+1:]
+	}
+
+	kind, ok := dithererKinds[name]
+	if !ok {
+		return DithererConfig{}, fmt.Errorf("unknown ditherer %q", name)
+	}
+
+	var params []float64
+	if args != "" {
+		for _, arg := range strings.Split(args, ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(arg), 64)
+			if err != nil {
+				return DithererConfig{}, fmt.Errorf("invalid parameter %q", arg)
+			}
+			params = append(params, v)
+		}
+	}
+
+	expected := map[DithererKind]int{DithererDitherize: 2, DithererMix: 1, DithererAdaptive: 2}[kind]
+	if len(params) != expected {
+		return DithererConfig{}, fmt.Errorf("%s ditherer takes %d parameters, got %d", name, expected, len(params))
+	}
+
+	c := DithererConfig{Kind: kind}
+	switch kind {
+	case DithererDitherize:
+		c.T1, c.T2 = params[0], params[1]
+	case DithererMix:
+		c.Ratio = params[0]
+	case DithererAdaptive:
+		c.Lower, c.Upper = params[0], params[1]
+	}
+	return c, nil
+}
+
+// NewDitherer builds a Ditherer from a palette. Every kind needs the 16 EGA
+// colors, except DithererNearest, which can use a palette of any size.
+func (c DithererConfig) NewDitherer(pal color.Palette) (*Ditherer, error) {
+	if c.Kind == DithererNearest {
+		if len(pal) == 0 || len(pal) > 256 {
+			return nil, fmt.Errorf("palette has %d colors", len(pal))
+		}
+		return NewNearestDitherer(pal), nil
+	}
+	if len(pal) < 16 {
+		return nil, fmt.Errorf("palette has %d colors, expected at least 16", len(pal))
+	}
+	pal = pal[:16]
+
+	switch c.Kind {
+	case DithererPlain:
+		return &Ditherer{Palette: pal}, nil
+	case DithererDitherize:
+		return NewDitherizer(pal, c.T1, c.T2), nil
+	case DithererMix:
+		return NewMixDitherer(pal, c.Ratio), nil
+	case DithererAdaptive:
+		return NewAdaptiveDithering(pal, c.Lower, c.Upper), nil
+	}
+	return nil, fmt.Errorf("unknown ditherer kind %d", c.Kind)
+}
+
+// LoadDitherer reads a palette file, in the format of its extension, and
+// builds a Ditherer from it.
+func LoadDitherer(r io.Reader, name string, c DithererConfig) (*Ditherer, error) {
+	format, err := PaletteFormatOf(name)
+	if err != nil {
+		return nil, err
+	}
+	pal, err := ReadPalette(r, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return c.NewDitherer(pal)
+}
+
+// ReadDriverTable reads the table of a display driver, as 256 lines of two
+// hex digits, one for each pair of EGA colors in order. The first digit is
+// the color drawn where x+y is even. Empty lines and lines that start with
+// ';' are skipped.
+func ReadDriverTable(r io.Reader) (DriverTable, error) {
+	var (
+		table   DriverTable
+		n       int
+		scanner = bufio.NewScanner(r)
+	)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, ";") {
+			continue
+		}
+		v, err := strconv.ParseUint(text, 16, 8)
+		if err != nil || len(text) != 2 {
+			return table, fmt.Errorf("line %d: invalid entry %q", line, text)
+		}
+		if n == len(table) {
+			return table, fmt.Errorf("line %d: more than %d entries", line, len(table))
+		}
+		table[n] = [2]uint8{uint8(v >> 4), uint8(v & 0xF)}
+		n++
+	}
+	if err := scanner.Err(); err != nil {
+		return table, err
+	}
+	if n != len(table) {
+		return table, fmt.Errorf("got %d entries, expected %d", n, len(table))
+	}
+	return table, nil
+}
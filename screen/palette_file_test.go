@@ -0,0 +1,110 @@
+package screen
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestPaletteFormats(t *testing.T) {
+	for _, name := range []string{"ega.gpl", "ega.pal", "ega.act", "ega.hex"} {
+		t.Run(name, func(t *testing.T) {
+			format, err := PaletteFormatOf(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b bytes.Buffer
+			if err := WritePalette(&b, DefaultPalettes.EGA, format, "EGA"); err != nil {
+				t.Fatal(err)
+			}
+			pal, err := ReadPalette(&b, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(pal) != len(DefaultPalettes.EGA) {
+				t.Fatalf("got %d colors, expected %d", len(pal), len(DefaultPalettes.EGA))
+			}
+			for i, c := range pal {
+				expected := color.RGBAModel.Convert(DefaultPalettes.EGA[i])
+				if color.RGBAModel.Convert(c) != expected {
+					t.Errorf("color %d: got %v, expected %v", i, c, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestReadPaletteHex(t *testing.T) {
+	pal, err := ReadPalette(strings.NewReader("#000000\n\nFF8000\n"), PaletteHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := color.RGBA{R: 0xFF, G: 0x80, A: 0xFF}
+	if len(pal) != 2 || color.RGBAModel.Convert(pal[1]) != expected {
+		t.Errorf("got %v", pal)
+	}
+}
+
+func TestLoadDitherer(t *testing.T) {
+	var b bytes.Buffer
+	if err := WritePalette(&b, DefaultPalettes.EGA, PaletteGPL, "EGA"); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ParseDithererConfig("mix:0.25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := LoadDitherer(&b, "ega.gpl", c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := NewMixDitherer(DefaultPalettes.EGA, 0.25)
+	if len(d.Palette) != len(expected.Palette) {
+		t.Fatalf("got %d colors, expected %d", len(d.Palette), len(expected.Palette))
+	}
+	for i := range d.Palette {
+		if color.RGBAModel.Convert(d.Palette[i]) != color.RGBAModel.Convert(expected.Palette[i]) {
+			t.Errorf("color %d differs", i)
+		}
+	}
+
+	for _, invalid := range []string{"mix", "ditherize:1", "adaptive:a,b", "unknown"} {
+		if _, err := ParseDithererConfig(invalid); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
+
+func TestReadDriverTable(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("; a test table\n")
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%x%x\n", i%4, i/64)
+	}
+	table, err := ReadDriverTable(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDriverDitherer(DefaultPalettes.CGA, table)
+	for _, c := range []uint8{0x00, 0x0F, 0x91, 0xFF} {
+		if c1, c2 := d.GetMapping(c); c1 != c%4 || c2 != c/64 {
+			t.Errorf("0x%02x: got %d,%d, expected %d,%d", c, c1, c2, c%4, c/64)
+		}
+		if c1 := d.DitherAt(0, 0, c); c1 != c%4 {
+			t.Errorf("0x%02x: got %d where x+y is even, expected %d", c, c1, c%4)
+		}
+	}
+
+	for _, invalid := range []string{"", "0\n", "000\n", strings.Repeat("00\n", 257)} {
+		if _, err := ReadDriverTable(strings.NewReader(invalid)); err == nil {
+			t.Errorf("%.8q: expected an error", invalid)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package screen
+
+// drawPattern calls plot for each pixel of a brush, the same way as the
+// interpreter. A brush covers a box that is size*2+2 pixels wide, and
+// size*2+1 pixels high. A box that would cross the top or left edge is moved
+// inside of the picture, and one that crosses the right or bottom edge is
+// clipped.
+//
+// Circles are a bitmap of the box that is read one bit for each pixel of the
+// clipped box, so the bitmap of a clipped circle is read across its rows.
+// Textured brushes take one bit of noise for each pixel of the brush that is
+// drawn.
+//
+// This follows GfxPicture::vectorPattern, vectorPatternTexturedBox and
+// vectorPatternTexturedCircle of ScummVM, in engines/sci/graphics/picture.cpp.
+func drawPattern(cx, cy, size int, isRect, isSolid bool, seed uint8, plot func(x, y int)) {
+	var (
+		noise  = newPatternNoise(seed)
+		width  = size*2 + 2
+		height = size*2 + 1
+		left   = clampInt(0, 320, cx-size)
+		top    = clampInt(0, 190, cy-size)
+		right  = left + width
+		bottom = top + height
+		bitmap = circleBitmaps[size]
+	)
+	if right > 320 {
+		right = 320
+	}
+	if bottom > 190 {
+		bottom = 190
+	}
+
+	i := 0
+	for py := top; py < bottom; py++ {
+		for px := left; px < right; px, i = px+1, i+1 {
+			if !isRect {
+				// the bitmap does not store the right-most column of the box
+				row, col := i/width, i%width
+				if col >= height || (bitmap[row]>>uint(height-1-col))&1 == 0 {
+					continue
+				}
+			}
+			if isSolid || noise.next() {
+				plot(px, py)
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package screen
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPatternRegression checks brushes at and away from the edges of a
+// picture. The expected pixels are this package's own output, from a port of
+// the loops of ScummVM's GfxPicture::vectorPattern, and only catch changes to
+// it. TestPatternCaptures compares brushes with those of an interpreter.
+func TestPatternRegression(t *testing.T) {
+	tests := []struct {
+		name     string
+		cx, cy   int
+		size     int
+		isRect   bool
+		seed     uint8
+		window   image.Rectangle
+		expected string
+	}{
+		{
+			name: "wrapping rect",
+			cx:   100, cy: 100, size: 7, isRect: true, seed: 13,
+			window: image.Rect(92, 92, 110, 109),
+			expected: `
+..................
+..X.......X....X..
+.....X..X.X.......
+...X...X..X..X..X.
+.....X.....X.X.X..
+..X..X.X...X.X....
+...X.....X..X.....
+.X.X...X...X....X.
+...X....X.....X...
+...X....X.X..X..X.
+....X.X..X.X.X..X.
+....X...X...X.....
+.X......X..X...X..
+..X.X...X......X..
+...X.X.X.X......X.
+..X....X..X.......
+..................
+`,
+		},
+		{
+			name: "clipped circle",
+			cx:   318, cy: 50, size: 4, isRect: false, seed: 5,
+			window: image.Rect(310, 44, 320, 56),
+			expected: `
+..........
+..........
+........X.
+..........
+......X...
+........X.
+.......X..
+.......X.X
+......X...
+....X...X.
+....X...X.
+..........
+`,
+		},
+		{
+			name: "moved circle",
+			cx:   2, cy: 2, size: 3, isRect: false, seed: 40,
+			window: image.Rect(0, 0, 9, 9),
+			expected: `
+.........
+.X..X....
+.........
+...X.X...
+.....X...
+..X......
+.........
+.........
+.........
+`,
+		},
+		{
+			name: "bottom rect",
+			cx:   60, cy: 188, size: 2, isRect: true, seed: 70,
+			window: image.Rect(56, 184, 66, 190),
+			expected: `
+..........
+..........
+..X....X..
+...X...X..
+.....X....
+....X..X..
+`,
+		},
+	}
+
+	bounds := image.Rect(0, 0, 320, 190)
+	scalers := map[string]struct {
+		Scaler
+		x, y int
+	}{
+		"1x1": {Scaler1x1{}, 1, 1},
+		"5x6": {Scaler5x6{}, 5, 6},
+	}
+
+	for _, tt := range tests {
+		for name, s := range scalers {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				pic := s.NewPic(bounds)
+				visual := pic.Visual()
+				visual.Clear(0xFF)
+				visual.Pattern(tt.cx, tt.cy, tt.size, tt.isRect, false, tt.seed, 0x00)
+
+				img := visual.Image().(*image.Paletted)
+				var b strings.Builder
+				for y := tt.window.Min.Y; y < tt.window.Max.Y; y++ {
+					for x := tt.window.Min.X; x < tt.window.Max.X; x++ {
+						if img.ColorIndexAt(x*s.x, y*s.y) == 0 {
+							b.WriteByte('X')
+						} else {
+							b.WriteByte('.')
+						}
+					}
+					b.WriteByte('\n')
+				}
+
+				if actual, expected := b.String(), strings.TrimPrefix(tt.expected, "\n"); actual != expected {
+					t.Errorf("got:\n%sexpected:\n%s", actual, expected)
+				}
+			})
+		}
+	}
+}
+
+// TestPatternCaptures compares brushes with screenshots of an interpreter, in
+// the directory named by SCI_PATTERN_CAPTURES. Each capture is a PNG of the
+// 320x190 picture area, without the menu bar, after the interpreter drew a
+// picture of a white background and one textured brush in black. The name of
+// a capture gives the brush, as cx_cy_size_shape_seed.png where shape is
+// "rect" or "circle", and seed is the texture of the brush.
+//
+// Captures can be made with ScummVM or DOSBox running an SCI0 game whose
+// first picture was replaced with such a picture. A useful set has every
+// size, a few seeds, and brushes cut off by each edge.
+func TestPatternCaptures(t *testing.T) {
+	dir := os.Getenv("SCI_PATTERN_CAPTURES")
+	if dir == "" {
+		t.Skip("SCI_PATTERN_CAPTURES is not set")
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Skip("no captures in", dir)
+	}
+
+	bounds := image.Rect(0, 0, 320, 190)
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".png")
+		t.Run(name, func(t *testing.T) {
+			var (
+				cx, cy, size int
+				shape        string
+				seed         uint8
+			)
+			if _, err := fmt.Sscanf(strings.Replace(name, "_", " ", -1), "%d %d %d %s %d", &cx, &cy, &size, &shape, &seed); err != nil {
+				t.Fatalf("bad capture name: %v", err)
+			}
+			if shape != "rect" && shape != "circle" {
+				t.Fatalf("bad capture shape %q", shape)
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			capture, err := png.Decode(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if capture.Bounds().Size() != bounds.Size() {
+				t.Fatalf("got capture of %v, expected %v", capture.Bounds().Size(), bounds.Size())
+			}
+
+			pic := Scaler1x1{}.NewPic(bounds)
+			visual := pic.Visual()
+			visual.Clear(0xFF)
+			visual.Pattern(cx, cy, size, shape == "rect", false, seed, 0x00)
+			img := visual.Image().(*image.Paletted)
+
+			min := capture.Bounds().Min
+			for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					r, g, b, _ := capture.At(min.X+x, min.Y+y).RGBA()
+					captured := r|g|b < 0x8000
+					if drawn := img.ColorIndexAt(x, y) == 0; drawn != captured {
+						t.Fatalf("%d,%d: drawn is %v, captured is %v", x, y, drawn, captured)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPatternNoiseWraps(t *testing.T) {
+	n := patternNoise(0xFF)
+	n.next()
+	if n != 0 {
+		t.Errorf("got position %d, expected 0", n)
+	}
+}
@@ -0,0 +1,76 @@
+package screen
+
+import (
+	"image"
+	"image/color"
+
+	clr "github.com/lucasb-eyer/go-colorful"
+)
+
+// PairPalette returns the apparent color of each of the 256 dithered pairs,
+// the 50/50 mix of its two colors.
+func (d *Ditherer) PairPalette() color.Palette {
+	pal := DefaultPalettes.EGA
+	if d != nil && d.Palette != nil {
+		pal = d.Palette
+	}
+
+	pairs := make(color.Palette, 256)
+	for i := range pairs {
+		c1, c2 := d.unpack(uint8(i))
+		if int(c1) >= len(pal) || int(c2) >= len(pal) {
+			pairs[i] = color.Black
+			continue
+		}
+		pairs[i] = linearMix(pal[c1], pal[c2])
+	}
+	return pairs
+}
+
+// linearMix averages the light of two colors, which is how a 50/50 dither of
+// them appears.
+func linearMix(c1, c2 color.Color) color.Color {
+	clr1, _ := clr.MakeColor(c1)
+	clr2, _ := clr.MakeColor(c2)
+	r1, g1, b1 := clr1.LinearRgb()
+	r2, g2, b2 := clr2.LinearRgb()
+	return clr.LinearRgb((r1+r2)/2, (g1+g2)/2, (b1+b2)/2).Clamped()
+}
+
+// Quantize maps each pixel of img to the dithered pair whose mix is the
+// closest match, measured in Lab space. The palette of the result is the
+// PairPalette of the ditherer. Alpha is ignored.
+func (d *Ditherer) Quantize(img image.Image) *image.Paletted {
+	pairs := d.PairPalette()
+	labs := make([][3]float64, len(pairs))
+	for i, c := range pairs {
+		c, _ := clr.MakeColor(c)
+		labs[i][0], labs[i][1], labs[i][2] = c.Lab()
+	}
+
+	bounds := img.Bounds()
+	dst := image.NewPaletted(bounds, pairs)
+	cache := map[color.RGBA]uint8{}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			key := color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 0xFF}
+
+			code, ok := cache[key]
+			if !ok {
+				src, _ := clr.MakeColor(key)
+				l, a, b := src.Lab()
+				best := -1.0
+				for i, lab := range labs {
+					dl, da, db := l-lab[0], a-lab[1], b-lab[2]
+					if dist := dl*dl + da*da + db*db; best < 0 || dist < best {
+						best, code = dist, uint8(i)
+					}
+				}
+				cache[key] = code
+			}
+			dst.Pix[dst.PixOffset(x, y)] = code
+		}
+	}
+	return dst
+}
@@ -0,0 +1,366 @@
+package screen
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+)
+
+// frozenBuffer5x6 is the visual layer of Scaler5x6 from before it was drawn
+// by ScalerNxM, kept to check that ScalerNxM{X: 5, Y: 6} draws the same. The
+// textured branch of Pattern is left out, as the noise that it used has since
+// been replaced, and textured brushes are checked by TestPatternRegression.
+type frozenBuffer5x6 struct {
+	*image.Paletted
+	*Ditherer
+	TextureMap
+
+	fillBuffer []uint8
+	bounds     image.Rectangle
+	stack      []point
+}
+
+func newFrozenBuffer5x6(bounds image.Rectangle, ditherer *Ditherer, textures TextureMap) frozenBuffer5x6 {
+	fillBuffer := make([]uint8, bounds.Dx()*bounds.Dy())
+	for i := range fillBuffer {
+		fillBuffer[i] = 15
+	}
+	return frozenBuffer5x6{
+		Paletted:   image.NewPaletted(image.Rect(0, 0, bounds.Dx()*5, bounds.Dy()*6), ditherer.Palette),
+		Ditherer:   ditherer,
+		TextureMap: textures,
+		fillBuffer: fillBuffer,
+		bounds:     image.Rect(0, 0, bounds.Dx(), bounds.Dy()),
+	}
+}
+
+func (b frozenBuffer5x6) Clear(color uint8) {
+	for i, max := 0, len(b.Pix); i < max; i++ {
+		y := i / b.Stride / 320
+		x := i % b.Stride / 320
+		b.Pix[i] = b.DitherAt(x, y, color)
+	}
+}
+
+func (b frozenBuffer5x6) plot(x, y int, color uint8) {
+	{
+		c1, c2 := color&0b1111, color>>4
+		b.fillBuffer[b.bounds.Dx()*y+x] = dither5050(x, y, c1, c2)
+	}
+
+	px, py := x*5, y*6
+	texture, ok := b.TextureMap[color]
+
+	if !ok || texture == nil {
+		c := b.DitherAt(x, y, color)
+		for h := 0; h < 6; h++ {
+			for w := 0; w < 5; w++ {
+				dx, dy := px+w, py+h
+				offset := dy*b.Stride + dx
+				b.Pix[offset] = c
+			}
+		}
+	} else {
+		yTexLen := len(texture)
+		yti := y % yTexLen
+		xTexLen := len(texture[yti])
+		xti := x % xTexLen
+		tex := texture[yti][xti]
+
+		c1, c2 := b.GetMapping(color)
+
+		for h := 0; h < 6; h++ {
+			for w := 0; w < 5; w++ {
+				dx, dy := px+w, py+h
+				offset := dy*b.Stride + dx
+				if tex[h][w] {
+					b.Pix[offset] = c1
+				} else {
+					b.Pix[offset] = c2
+				}
+			}
+		}
+	}
+}
+
+func (b frozenBuffer5x6) Plot(x, y int, color uint8) {
+	if !(image.Point{X: x, Y: y}).In(b.bounds) {
+		return
+	}
+	b.plot(x, y, color)
+}
+
+func (b frozenBuffer5x6) Line(x1, y1, x2, y2 int, color uint8) {
+	var (
+		left   = clampInt(0, 319, x1)
+		top    = clampInt(0, 189, y1)
+		right  = clampInt(0, 319, x2)
+		bottom = clampInt(0, 189, y2)
+	)
+
+	switch {
+	case left == right:
+		swapIf(&top, &bottom, top > bottom)
+		for y := top; y <= bottom; y++ {
+			b.plot(left, y, color)
+		}
+	case top == bottom:
+		swapIf(&right, &left, right > left)
+		for x := right; x <= left; x++ {
+			b.plot(x, top, color)
+		}
+	default:
+		dx, dy := right-left, bottom-top
+		stepX, stepY := ((dx>>15)<<1)+1, ((dy>>15)<<1)+1
+
+		dx, dy = absInt(dx)<<1, absInt(dy)<<1
+
+		b.plot(left, top, color)
+		b.plot(right, bottom, color)
+
+		if dx > dy {
+			fraction := dy - (dx >> 1)
+			for left != right {
+				if fraction >= 0 {
+					top += stepY
+					fraction -= dx
+				}
+				left += stepX
+				fraction += dy
+				b.plot(left, top, color)
+			}
+		} else {
+			fraction := dx - (dy >> 1)
+			for top != bottom {
+				if fraction >= 0 {
+					left += stepX
+					fraction -= dy
+				}
+				top += stepY
+				fraction += dx
+				b.plot(left, top, color)
+			}
+		}
+	}
+}
+
+func (b frozenBuffer5x6) Pattern(cx, cy, size int, isRect bool, color uint8) {
+	var (
+		width  = size*2 + 2
+		height = size*2 + 1
+		left   = clampInt(0, 320, cx-size)
+		top    = clampInt(0, 190, cy-size)
+	)
+
+	if left+width > 320 {
+		width = 320 - left
+	}
+
+	if top+height > 190 {
+		height = 190 - top
+	}
+
+	if isRect {
+		right, bottom := left+width, top+height
+
+		for py := top; py < bottom; py++ {
+			for px := left; px < right; px++ {
+				b.plot(px, py, color)
+			}
+		}
+	} else {
+		bitmap := circleBitmaps[size]
+		size := len(bitmap)
+		for y, row := range bitmap {
+			py := top + y
+			if py >= 190 {
+				break
+			}
+
+			for x := 0; x < size; x++ {
+				px := left + x
+				if px >= 320 {
+					break
+				}
+				if ((row >> (size - (x + 1))) & 1) == 1 {
+					b.plot(px, py, color)
+				}
+			}
+		}
+	}
+}
+
+func (b frozenBuffer5x6) isLegal(p point, legalColor uint8) bool {
+	return b.fillBuffer[p.y*b.bounds.Dx()+p.x] == legalColor
+}
+
+func (b frozenBuffer5x6) Fill(cx, cy int, legalColor uint8, color uint8) {
+	var (
+		p     point
+		stack = b.stack
+	)
+
+	stack = append(stack, point{cx, cy})
+
+	for len(stack) > 0 {
+		p, stack = stack[0], stack[1:]
+
+		var (
+			x, y = p.x, p.y
+		)
+
+		if !b.isLegal(p, legalColor) {
+			continue
+		}
+
+		b.plot(x, y, color)
+
+		if down := (point{x, y + 1}); down.y < 190 {
+			if b.isLegal(down, legalColor) {
+				stack = append(stack, down)
+			}
+		}
+
+		if up := (point{x, y - 1}); up.y >= 0 {
+			if b.isLegal(up, legalColor) {
+				stack = append(stack, up)
+			}
+		}
+
+		for dx := x + 1; dx < 320; dx++ {
+			right := point{dx, y}
+			if !b.isLegal(right, legalColor) {
+				break
+			}
+
+			b.plot(right.x, right.y, color)
+			if down := (point{dx, y + 1}); down.y < 190 {
+				if b.isLegal(down, legalColor) {
+					stack = append(stack, down)
+				}
+			}
+			if up := (point{dx, y - 1}); up.y >= 0 {
+				if b.isLegal(up, legalColor) {
+					stack = append(stack, up)
+				}
+			}
+		}
+
+		for dx := x - 1; dx >= 0; dx-- {
+			left := point{dx, y}
+			if !b.isLegal(left, legalColor) {
+				break
+			}
+
+			b.plot(left.x, left.y, color)
+			if down := (point{dx, y + 1}); down.y < 190 {
+				if b.isLegal(down, legalColor) {
+					stack = append(stack, down)
+				}
+			}
+			if up := (point{dx, y - 1}); up.y >= 0 {
+				if b.isLegal(up, legalColor) {
+					stack = append(stack, up)
+				}
+			}
+		}
+	}
+}
+
+func TestScaler5x6MatchesFrozen(t *testing.T) {
+	bounds := image.Rect(0, 0, 320, 190)
+	textures := TextureMap{0x4c: DefaultTextures.Dither2}
+	expected := newFrozenBuffer5x6(bounds, DefaultDitherers.EGA, textures)
+	actual := Scaler5x6{Ditherer: DefaultDitherers.EGA, TextureMap: textures}.NewPic(bounds).Visual()
+
+	compare := func(step string) {
+		t.Helper()
+		a := actual.Image().(*image.Paletted)
+		if a.Rect != expected.Rect {
+			t.Fatalf("%s: got bounds %v, expected %v", step, a.Rect, expected.Rect)
+		}
+		for i := range a.Pix {
+			if a.Pix[i] != expected.Pix[i] {
+				t.Fatalf("%s: pixel %d,%d differs", step, i%a.Stride, i/a.Stride)
+			}
+		}
+	}
+
+	// a solid Clear is drawn the same, see TestScaler5x6ClearDithers for
+	// dithered ones
+	expected.Clear(0xFF)
+	actual.Clear(0xFF)
+	compare("clear")
+
+	r := rand.New(rand.NewSource(1))
+	colors := []uint8{0x00, 0x41, 0x9a, 0x4c, 0x77}
+	for i := 0; i < 30; i++ {
+		x1, y1, x2, y2 := r.Intn(340)-10, r.Intn(210)-10, r.Intn(340)-10, r.Intn(210)-10
+		c := colors[i%len(colors)]
+		expected.Line(x1, y1, x2, y2, c)
+		actual.Line(x1, y1, x2, y2, c)
+	}
+	expected.Line(0, 100, 319, 100, 0x01)
+	actual.Line(0, 100, 319, 100, 0x01)
+	expected.Line(160, 0, 160, 189, 0x01)
+	actual.Line(160, 0, 160, 189, 0x01)
+	compare("lines")
+
+	for i := 0; i < 20; i++ {
+		cx, cy, size := 8+r.Intn(304), 8+r.Intn(174), r.Intn(8)
+		isRect := i%2 == 0
+		c := colors[i%len(colors)]
+		expected.Pattern(cx, cy, size, isRect, c)
+		actual.Pattern(cx, cy, size, isRect, true, 0, c)
+	}
+	compare("patterns")
+
+	for i := 0; i < 20; i++ {
+		x, y := r.Intn(320), r.Intn(190)
+		c := []uint8{0x22, 0x9a, 0x4c, 0x3b}[i%4]
+		expected.Fill(x, y, 0xF, c)
+		actual.Fill(x, y, 0xF, c)
+	}
+	compare("fills")
+
+	expected.Plot(5, 5, 0x99)
+	Plot(actual, 5, 5, 0x99)
+	expected.Plot(320, 5, 0x99)
+	Plot(actual, 320, 5, 0x99)
+	compare("plots")
+}
+
+// TestScaler5x6ClearDithers checks that Clear dithers each pixel of the
+// picture by its own position, and that fills then treat the picture as
+// that color. Before Scaler5x6 was drawn by ScalerNxM, Clear dithered the
+// scaled image in bands of 320 pixels, and left the fill buffer as it was.
+func TestScaler5x6ClearDithers(t *testing.T) {
+	bounds := image.Rect(0, 0, 320, 190)
+	pic := Scaler5x6{}.NewPic(bounds)
+	v := pic.Visual()
+	v.Clear(0x41)
+
+	img := v.Image().(*image.Paletted)
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			if c, expected := img.ColorIndexAt(x, y), DefaultDitherers.EGA.DitherAt(x/5, y/6, 0x41); c != expected {
+				t.Fatalf("%d,%d: got %d, expected %d", x, y, c, expected)
+			}
+		}
+	}
+
+	// the fill buffer holds the cleared color, whose pixels are not
+	// connected, so a fill of one of its colors covers a single pixel
+	v.Fill(10, 10, 1, 0x22)
+	for y := 54; y < 72; y++ {
+		for x := 45; x < 60; x++ {
+			expected := DefaultDitherers.EGA.DitherAt(x/5, y/6, 0x41)
+			if x/5 == 10 && y/6 == 10 {
+				expected = 2
+			}
+			if c := img.ColorIndexAt(x, y); c != expected {
+				t.Fatalf("%d,%d: got %d, expected %d", x, y, c, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package screen
+
+import (
+	"image"
+)
+
+// ScalerNxM scales the visual layer of a picture by X horizontally and Y
+// vertically. The priority and control layers are left at their original
+// size.
+//
+// Colors that have a texture in the TextureMap are drawn with it, rather than
+// with the Ditherer. Texture blocks are 5x6, and are stretched to fit other
+// factors.
+type ScalerNxM struct {
+	*Ditherer
+	TextureMap
+	X, Y int
+}
+
+func (s ScalerNxM) NewPic(bounds image.Rectangle) Pic {
+	scaleX, scaleY := s.X, s.Y
+	if scaleX < 1 {
+		scaleX = 1
+	}
+	if scaleY < 1 {
+		scaleY = 1
+	}
+
+	normBounds := image.Rect(0, 0, bounds.Dx(), bounds.Dy())
+	scaledBounds := image.Rect(0, 0, bounds.Dx()*scaleX, bounds.Dy()*scaleY)
+
+	ditherer := s.Ditherer
+	if ditherer == nil {
+		ditherer = DefaultDitherers.EGA
+	}
+
+	priorityDitherer := &Ditherer{
+		Palette:  DefaultPalettes.Depth,
+		DitherFn: noDither,
+	}
+
+	controlDitherer := &Ditherer{
+		Palette:  DefaultPalettes.EGA,
+		DitherFn: noDither,
+	}
+
+	fillBuffer := make([]uint8, bounds.Dx()*bounds.Dy())
+	for i, max := 0, len(fillBuffer); i < max; i++ {
+		fillBuffer[i] = 15
+	}
+
+	return &picLayers{
+		visual: &bufferNxM{
+			Paletted:   image.NewPaletted(scaledBounds, ditherer.Palette),
+			Ditherer:   ditherer,
+			TextureMap: s.TextureMap,
+			scaleX:     scaleX,
+			scaleY:     scaleY,
+
+			fillBuffer: fillBuffer,
+			bounds:     normBounds,
+			spans:      newSpanFill(normBounds.Dx(), normBounds.Dy()),
+		},
+		priority: &buffer1x1{
+			Paletted: image.NewPaletted(normBounds, priorityDitherer.Palette),
+			Ditherer: priorityDitherer,
+		},
+		control: &buffer1x1{
+			Paletted: image.NewPaletted(normBounds, controlDitherer.Palette),
+			Ditherer: controlDitherer,
+		},
+	}
+}
+
+type bufferNxM struct {
+	*image.Paletted
+	*Ditherer
+	TextureMap
+	scaleX, scaleY int
+
+	// fillBuffer holds the unscaled picture, as it would be drawn by the
+	// interpreter, so that fills spread the same way at any scale.
+	fillBuffer []uint8
+	bounds     image.Rectangle
+	spans      *spanFill
+}
+
+func (b bufferNxM) Clear(color uint8) {
+	c1, c2 := color&0b1111, color>>4
+	for i, max := 0, len(b.fillBuffer); i < max; i++ {
+		x, y := i%b.bounds.Dx(), i/b.bounds.Dx()
+		b.fillBuffer[i] = dither5050(x, y, c1, c2)
+	}
+
+	for i, max := 0, len(b.Pix); i < max; i++ {
+		y := i / b.Stride / b.scaleY
+		x := i % b.Stride / b.scaleX
+		b.Pix[i] = b.DitherAt(x, y, color)
+	}
+}
+
+func (b bufferNxM) Image() image.Image {
+	return b.Paletted
+}
+
+func (b bufferNxM) plot(x, y int, color uint8) {
+	{
+		c1, c2 := color&0b1111, color>>4
+		b.fillBuffer[b.bounds.Dx()*y+x] = dither5050(x, y, c1, c2)
+	}
+
+	px, py := x*b.scaleX, y*b.scaleY
+	texture, ok := b.TextureMap[color]
+
+	if !ok || texture == nil {
+		c := b.DitherAt(x, y, color)
+		for h := 0; h < b.scaleY; h++ {
+			offset := (py+h)*b.Stride + px
+			for w := 0; w < b.scaleX; w++ {
+				b.Pix[offset+w] = c
+			}
+		}
+	} else {
+		yTexLen := len(texture)
+		yti := y % yTexLen
+		xTexLen := len(texture[yti])
+		xti := x % xTexLen
+		tex := texture[yti][xti]
+
+		c1, c2 := b.GetMapping(color)
+
+		for h := 0; h < b.scaleY; h++ {
+			offset := (py+h)*b.Stride + px
+			row := tex[h*len(tex)/b.scaleY]
+			for w := 0; w < b.scaleX; w++ {
+				if row[w*len(row)/b.scaleX] {
+					b.Pix[offset+w] = c1
+				} else {
+					b.Pix[offset+w] = c2
+				}
+			}
+		}
+	}
+}
+
+func (b bufferNxM) Plot(x, y int, color uint8) {
+	if !(image.Point{X: x, Y: y}).In(b.bounds) {
+		return
+	}
+	b.plot(x, y, color)
+}
+
+func (b bufferNxM) Line(x1, y1, x2, y2 int, color uint8) {
+	drawLine(x1, y1, x2, y2, func(x, y int) {
+		b.plot(x, y, color)
+	})
+}
+
+func (b bufferNxM) Pattern(cx, cy, size int, isRect bool, isSolid bool, seed uint8, color uint8) {
+	drawPattern(cx, cy, size, isRect, isSolid, seed, func(x, y int) {
+		b.plot(x, y, color)
+	})
+}
+
+func (b bufferNxM) Fill(cx, cy int, legalColor uint8, color uint8) {
+	b.spans.fill(cx, cy, func(x, y int) bool {
+		return b.fillBuffer[y*b.bounds.Dx()+x] == legalColor
+	}, func(x, y int) {
+		b.plot(x, y, color)
+	})
+}
@@ -0,0 +1,56 @@
+package screen
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func drawTestPic(pic Pic) {
+	v := pic.Visual()
+	v.Clear(0xFF)
+	v.Line(10, 10, 300, 150, 0x41)
+	v.Line(0, 100, 319, 100, 0x01)
+	v.Pattern(160, 60, 7, false, false, 33, 0x22)
+	v.Pattern(40, 160, 5, true, true, 0, 0x5e)
+	v.Fill(200, 20, 0xF, 0x9a)
+	v.Fill(20, 180, 0xF, 0x66)
+}
+
+func TestScalerNxMMatches1x1(t *testing.T) {
+	bounds := image.Rect(0, 0, 320, 190)
+	expected := Scaler1x1{}.NewPic(bounds)
+	actual := ScalerNxM{X: 1, Y: 1}.NewPic(bounds)
+	drawTestPic(expected)
+	drawTestPic(actual)
+
+	e := expected.Visual().Image().(*image.Paletted)
+	a := actual.Visual().Image().(*image.Paletted)
+	if !bytes.Equal(e.Pix, a.Pix) {
+		t.Error("1x1 visual layer differs from Scaler1x1")
+	}
+}
+
+func TestScalerNxMStretchesTextures(t *testing.T) {
+	bounds := image.Rect(0, 0, 320, 190)
+	textures := TextureMap{0x4c: DefaultTextures.Dither2}
+	small := ScalerNxM{TextureMap: textures, X: 5, Y: 6}.NewPic(bounds)
+	large := ScalerNxM{TextureMap: textures, X: 10, Y: 12}.NewPic(bounds)
+	for _, pic := range []Pic{small, large} {
+		pic.Visual().Clear(0xFF)
+		pic.Visual().Pattern(100, 100, 7, true, true, 0, 0x4c)
+	}
+
+	s := small.Visual().Image().(*image.Paletted)
+	l := large.Visual().Image().(*image.Paletted)
+	if l.Rect != image.Rect(0, 0, 3200, 2280) {
+		t.Fatalf("got bounds %v", l.Rect)
+	}
+	for y := 0; y < l.Rect.Dy(); y++ {
+		for x := 0; x < l.Rect.Dx(); x++ {
+			if got, want := l.ColorIndexAt(x, y), s.ColorIndexAt(x/2, y/2); got != want {
+				t.Fatalf("pixel %d,%d: got %d, expected %d", x, y, got, want)
+			}
+		}
+	}
+}
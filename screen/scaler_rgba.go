@@ -0,0 +1,160 @@
+package screen
+
+import (
+	"image"
+	"image/color"
+)
+
+// ScalerRGBA draws the visual layer in truecolor, scaled by X horizontally
+// and Y vertically. Rather than being dithered, each pair of colors is drawn
+// as a single color, so any blend can be used without a palette limit.
+type ScalerRGBA struct {
+	// Ditherer maps each pair to two colors of its palette. Its DitherFn is
+	// not used.
+	*Ditherer
+	// Blend returns the color that a pair is drawn with. When it is nil, the
+	// two colors are mixed 50/50 in linear light, see PairPalette.
+	Blend func(c1, c2 color.Color) color.Color
+	X, Y  int
+	// Smooth upscales the visual layer by cutting the corners of the pixels
+	// along diagonal edges, in the style of xBR, rather than repeating each
+	// pixel.
+	Smooth bool
+}
+
+func (s ScalerRGBA) NewPic(bounds image.Rectangle) Pic {
+	scaleX, scaleY := s.X, s.Y
+	if scaleX < 1 {
+		scaleX = 1
+	}
+	if scaleY < 1 {
+		scaleY = 1
+	}
+
+	pairs := s.Ditherer.PairPalette()
+	if s.Blend != nil {
+		pal := DefaultPalettes.EGA
+		if s.Ditherer != nil && s.Ditherer.Palette != nil {
+			pal = s.Ditherer.Palette
+		}
+		for i := range pairs {
+			c1, c2 := s.Ditherer.unpack(uint8(i))
+			if int(c1) < len(pal) && int(c2) < len(pal) {
+				pairs[i] = s.Blend(pal[c1], pal[c2])
+			}
+		}
+	}
+
+	// pairs are drawn as themselves, and looked up when the image is made
+	colors := make([]color.RGBA, 256)
+	for i := range pairs {
+		colors[i] = color.RGBAModel.Convert(pairs[i]).(color.RGBA)
+	}
+
+	pic := newPairPic(bounds, pairs)
+	pic.visual = &bufferRGBA{
+		Buffer: pic.visual,
+		colors: colors,
+		scaleX: scaleX,
+		scaleY: scaleY,
+		smooth: s.Smooth,
+	}
+	return pic
+}
+
+// bufferRGBA draws the pairs of colors at 1x1, and scales them to truecolor
+// when the image is made.
+type bufferRGBA struct {
+	Buffer
+	colors         []color.RGBA
+	scaleX, scaleY int
+	smooth         bool
+}
+
+func (b *bufferRGBA) Image() image.Image {
+	pairs := b.Buffer.Image().(*image.Paletted)
+	r := pairs.Rect
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx()*b.scaleX, r.Dy()*b.scaleY))
+
+	for dy := 0; dy < dst.Rect.Dy(); dy++ {
+		for dx := 0; dx < dst.Rect.Dx(); dx++ {
+			x, y := r.Min.X+dx/b.scaleX, r.Min.Y+dy/b.scaleY
+			c := b.colors[pairs.ColorIndexAt(x, y)]
+			if b.smooth {
+				u := (float64(dx%b.scaleX) + 0.5) / float64(b.scaleX)
+				v := (float64(dy%b.scaleY) + 0.5) / float64(b.scaleY)
+				c = b.smoothAt(pairs, x, y, u, v)
+			}
+			dst.SetRGBA(dx, dy, c)
+		}
+	}
+	return dst
+}
+
+// smoothAt returns the color at u, v within the pixel at x, y. Where two
+// neighbors on one corner of the pixel match each other, but not the pixel
+// or the neighbors across from them, the corner is cut along the line
+// between the middles of its sides, and is drawn in the neighbors' color.
+func (b *bufferRGBA) smoothAt(pairs *image.Paletted, x, y int, u, v float64) color.RGBA {
+	at := func(x, y int) uint8 {
+		r := pairs.Rect
+		return pairs.ColorIndexAt(clampInt(r.Min.X, r.Max.X-1, x), clampInt(r.Min.Y, r.Max.Y-1, y))
+	}
+	var (
+		center = at(x, y)
+		up     = at(x, y-1)
+		left   = at(x-1, y)
+		right  = at(x+1, y)
+		down   = at(x, y+1)
+	)
+
+	// the corner that u, v is in, and the distance of u, v from it
+	var side1, side2, across1, across2 uint8
+	var distance float64
+	switch {
+	case u < 0.5 && v < 0.5:
+		side1, side2, across1, across2 = up, left, right, down
+		distance = u + v
+	case u > 0.5 && v < 0.5:
+		side1, side2, across1, across2 = up, right, left, down
+		distance = (1 - u) + v
+	case u < 0.5 && v > 0.5:
+		side1, side2, across1, across2 = down, left, right, up
+		distance = u + (1 - v)
+	case u > 0.5 && v > 0.5:
+		side1, side2, across1, across2 = down, right, left, up
+		distance = (1 - u) + (1 - v)
+	default:
+		return b.colors[center]
+	}
+
+	if side1 != side2 || side1 == center || side1 == across1 || side2 == across2 {
+		return b.colors[center]
+	}
+
+	// blend across the width of one output pixel, so that the cut is smooth
+	scale := b.scaleX
+	if b.scaleY < scale {
+		scale = b.scaleY
+	}
+	t := (0.5-distance)*float64(scale) + 0.5
+	switch {
+	case t <= 0:
+		return b.colors[center]
+	case t >= 1:
+		return b.colors[side1]
+	}
+	return lerpRGBA(b.colors[center], b.colors[side1], t)
+}
+
+func lerpRGBA(c1, c2 color.RGBA, t float64) color.RGBA {
+	lerp := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*t + 0.5)
+	}
+	return color.RGBA{
+		R: lerp(c1.R, c2.R),
+		G: lerp(c1.G, c2.G),
+		B: lerp(c1.B, c2.B),
+		A: lerp(c1.A, c2.A),
+	}
+}
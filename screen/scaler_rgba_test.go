@@ -0,0 +1,60 @@
+package screen
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestScalerRGBA(t *testing.T) {
+	bounds := image.Rect(0, 0, 320, 190)
+	pic := ScalerRGBA{X: 2, Y: 2}.NewPic(bounds)
+	pic.Visual().Clear(0xFF)
+	pic.Visual().Pattern(100, 100, 3, true, true, 0, 0x14)
+
+	img := pic.Visual().Image().(*image.RGBA)
+	if img.Rect != image.Rect(0, 0, 640, 380) {
+		t.Fatalf("got bounds %v", img.Rect)
+	}
+	expected := color.RGBAModel.Convert(linearMix(DefaultPalettes.EGA[1], DefaultPalettes.EGA[4]))
+	for _, p := range []image.Point{{200, 200}, {201, 201}, {194, 194}} {
+		if c := img.At(p.X, p.Y); c != expected {
+			t.Errorf("%v: got %v, expected %v", p, c, expected)
+		}
+	}
+	if c := img.At(10, 10); c != color.RGBAModel.Convert(DefaultPalettes.EGA[15]) {
+		t.Errorf("got background %v", c)
+	}
+}
+
+func TestScalerRGBASmooth(t *testing.T) {
+	bounds := image.Rect(0, 0, 320, 190)
+	pic := ScalerRGBA{X: 4, Y: 4, Smooth: true}.NewPic(bounds)
+	v := pic.Visual()
+	v.Clear(0xFF)
+	// everything above the diagonal is blue
+	for y := 0; y < 20; y++ {
+		for x := y + 1; x < 20; x++ {
+			Plot(v, x, y, 0x11)
+		}
+	}
+
+	img := pic.Visual().Image().(*image.RGBA)
+	blue := color.RGBAModel.Convert(DefaultPalettes.EGA[1])
+	white := color.RGBAModel.Convert(DefaultPalettes.EGA[15])
+	tests := []struct {
+		x, y     int
+		expected color.Color
+	}{
+		{5*4 + 3, 5*4 + 0, blue},  // top-right corner of a white pixel on the diagonal is cut
+		{5*4 + 1, 5*4 + 1, white}, // its top-left corner is not
+		{6*4 + 0, 5*4 + 3, white}, // the bottom-left corner of the blue pixel next to it is cut
+		{6*4 + 0, 6*4 + 3, white},
+		{15*4 + 2, 2*4 + 2, blue},
+	}
+	for _, tt := range tests {
+		if c := img.At(tt.x, tt.y); c != tt.expected {
+			t.Errorf("%d,%d: got %v, expected %v", tt.x, tt.y, c, tt.expected)
+		}
+	}
+}
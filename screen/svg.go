@@ -0,0 +1,272 @@
+package screen
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SVGScaler draws the visual layer of a picture as vector shapes, that can
+// be written with WriteSVG. Solid rectangles are kept as rectangles, and
+// lines, circles, fills and textured brushes are traced into polygons around
+// the pixels that they cover, so that neighboring shapes meet without gaps.
+// Dithered colors are drawn with a 2x2 pattern.
+//
+// The visual layer is also drawn at 1x1, as fills depend on what has already
+// been drawn, and is returned by Image.
+type SVGScaler struct {
+	*Ditherer
+}
+
+func (s SVGScaler) NewPic(bounds image.Rectangle) Pic {
+	ditherer := s.Ditherer
+	if ditherer == nil {
+		ditherer = DefaultDitherers.EGA
+	}
+
+	pic := Scaler1x1{Ditherer: ditherer}.NewPic(bounds).(*picLayers)
+	pic.visual = &svgBuffer{
+		buffer1x1: &buffer1x1{
+			Paletted: image.NewPaletted(bounds, DefaultPalettes.EGA),
+			Ditherer: &Ditherer{Palette: DefaultPalettes.EGA},
+		},
+		ditherer: ditherer,
+		patterns: map[uint8]bool{},
+	}
+	return pic
+}
+
+// svgBuffer records shapes, and draws the undithered EGA picture that fills
+// are tested against.
+type svgBuffer struct {
+	*buffer1x1
+	ditherer *Ditherer
+	shapes   []string
+	patterns map[uint8]bool
+
+	// pixels is the path of the pixels that Plot is adding to, in pixelColor.
+	pixels     strings.Builder
+	pixelColor uint8
+}
+
+// paint returns the fill or stroke of a color.
+func (b *svgBuffer) paint(color uint8) string {
+	c1, c2 := b.ditherer.GetMapping(color)
+	if c1 == c2 {
+		return b.hex(c1)
+	}
+	b.patterns[color] = true
+	return fmt.Sprintf("url(#c%02x)", color)
+}
+
+func (b *svgBuffer) hex(i uint8) string {
+	r, g, bl, _ := b.ditherer.Palette[i].RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, bl>>8)
+}
+
+func (b *svgBuffer) add(format string, args ...interface{}) {
+	b.flush()
+	b.shapes = append(b.shapes, fmt.Sprintf(format, args...))
+}
+
+// flush adds the pixels drawn by Plot as a shape.
+func (b *svgBuffer) flush() {
+	if b.pixels.Len() == 0 {
+		return
+	}
+	b.shapes = append(b.shapes, fmt.Sprintf(`<path fill="%s" d="%s"/>`, b.paint(b.pixelColor), b.pixels.String()))
+	b.pixels.Reset()
+}
+
+func (b *svgBuffer) Clear(color uint8) {
+	b.buffer1x1.Clear(color)
+	b.shapes, b.patterns = nil, map[uint8]bool{}
+	b.pixels.Reset()
+	r := b.Rect
+	b.add(`<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`, r.Min.X, r.Min.Y, r.Dx(), r.Dy(), b.paint(color))
+}
+
+func (b *svgBuffer) Plot(x, y int, color uint8) {
+	if !(image.Point{X: x, Y: y}).In(b.Rect) {
+		return
+	}
+	b.buffer1x1.Plot(x, y, color)
+	// consecutive pixels of the same color share a path
+	if color != b.pixelColor {
+		b.flush()
+		b.pixelColor = color
+	}
+	fmt.Fprintf(&b.pixels, "M%d %dh1v1h-1z", x, y)
+}
+
+func (b *svgBuffer) Line(x1, y1, x2, y2 int, color uint8) {
+	b.buffer1x1.Line(x1, y1, x2, y2, color)
+
+	var pixels []image.Point
+	drawLine(x1, y1, x2, y2, func(x, y int) {
+		pixels = append(pixels, image.Point{X: x, Y: y})
+	})
+	b.polygon(b.trace(pixels), color)
+}
+
+func (b *svgBuffer) Pattern(cx, cy, size int, isRect, isSolid bool, seed uint8, color uint8) {
+	b.buffer1x1.Pattern(cx, cy, size, isRect, isSolid, seed, color)
+
+	if isRect && isSolid {
+		left, top := clampInt(0, 320, cx-size), clampInt(0, 190, cy-size)
+		b.add(`<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`, left, top, size*2+2, size*2+1, b.paint(color))
+		return
+	}
+
+	var pixels []image.Point
+	drawPattern(cx, cy, size, isRect, isSolid, seed, func(x, y int) {
+		pixels = append(pixels, image.Point{X: x, Y: y})
+	})
+	b.polygon(b.trace(pixels), color)
+}
+
+func (b *svgBuffer) Fill(cx, cy int, legalColor uint8, color uint8) {
+	var pixels []image.Point
+	b.buffer1x1.fill(cx, cy, legalColor, color, func(x, y int) {
+		pixels = append(pixels, image.Point{X: x, Y: y})
+	})
+	b.polygon(b.trace(pixels), color)
+}
+
+func (b *svgBuffer) polygon(d string, color uint8) {
+	if d == "" {
+		return
+	}
+	b.add(`<path fill="%s" d="%s"/>`, b.paint(color), d)
+}
+
+// trace returns a path around the edges of a set of pixels. Outlines run
+// clockwise and holes run counter-clockwise, so that the path is drawn with
+// the nonzero fill rule.
+func (b *svgBuffer) trace(pixels []image.Point) string {
+	if len(pixels) == 0 {
+		return ""
+	}
+	// a pixel may be drawn more than once
+	set := make(map[image.Point]bool, len(pixels))
+	unique := pixels[:0:0]
+	for _, p := range pixels {
+		if !set[p] {
+			set[p] = true
+			unique = append(unique, p)
+		}
+	}
+
+	// each edge of a pixel that does not touch another pixel of the set,
+	// from its start to its end, with the pixel on its right.
+	edges := map[image.Point][]image.Point{}
+	var starts []image.Point
+	addEdge := func(from, to image.Point) {
+		if len(edges[from]) == 0 {
+			starts = append(starts, from)
+		}
+		edges[from] = append(edges[from], to)
+	}
+	for _, p := range unique {
+		if !set[p.Add(image.Point{Y: -1})] {
+			addEdge(p, p.Add(image.Point{X: 1}))
+		}
+		if !set[p.Add(image.Point{X: 1})] {
+			addEdge(p.Add(image.Point{X: 1}), p.Add(image.Point{X: 1, Y: 1}))
+		}
+		if !set[p.Add(image.Point{Y: 1})] {
+			addEdge(p.Add(image.Point{X: 1, Y: 1}), p.Add(image.Point{Y: 1}))
+		}
+		if !set[p.Add(image.Point{X: -1})] {
+			addEdge(p.Add(image.Point{Y: 1}), p)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i].Y < starts[j].Y || starts[i].Y == starts[j].Y && starts[i].X < starts[j].X
+	})
+
+	var d strings.Builder
+	for _, start := range starts {
+		for len(edges[start]) > 0 {
+			loop := []image.Point{start}
+			at := start
+			for {
+				next := edges[at][0]
+				edges[at] = edges[at][1:]
+				at = next
+				if at == start {
+					break
+				}
+				loop = append(loop, at)
+			}
+			writeLoop(&d, loop)
+		}
+	}
+	return d.String()
+}
+
+// writeLoop writes a closed loop of points, leaving out the points that are
+// between two others on a straight line.
+func writeLoop(d *strings.Builder, loop []image.Point) {
+	n := len(loop)
+	first := true
+	for i, p := range loop {
+		prev, next := loop[(i+n-1)%n], loop[(i+1)%n]
+		if p.Sub(prev) == next.Sub(p) {
+			continue
+		}
+		if first {
+			fmt.Fprintf(d, "M%d %d", p.X, p.Y)
+			first = false
+		} else {
+			fmt.Fprintf(d, "L%d %d", p.X, p.Y)
+		}
+	}
+	d.WriteString("z")
+}
+
+// WriteSVG writes the visual layer of a picture that was drawn with
+// SVGScaler as an SVG document.
+func WriteSVG(w io.Writer, pic Pic) error {
+	b, ok := pic.Visual().(*svgBuffer)
+	if !ok {
+		return errors.New("picture was not drawn with an SVGScaler")
+	}
+
+	b.flush()
+
+	bw := bufio.NewWriter(w)
+	r := b.Rect
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="%d %d %d %d" width="%d" height="%d">`+"\n",
+		r.Min.X, r.Min.Y, r.Dx(), r.Dy(), r.Dx(), r.Dy())
+
+	if len(b.patterns) > 0 {
+		colors := make([]int, 0, len(b.patterns))
+		for c := range b.patterns {
+			colors = append(colors, int(c))
+		}
+		sort.Ints(colors)
+
+		bw.WriteString("<defs>\n")
+		for _, c := range colors {
+			// matches dither5050, with c1 where x and y are both even or odd
+			c1, c2 := b.ditherer.GetMapping(uint8(c))
+			fmt.Fprintf(bw, `<pattern id="c%02x" width="2" height="2" patternUnits="userSpaceOnUse">`, c)
+			fmt.Fprintf(bw, `<rect width="2" height="2" fill="%s"/>`, b.hex(c2))
+			fmt.Fprintf(bw, `<path fill="%s" d="M0 0h1v1h-1zM1 1h1v1h-1z"/>`, b.hex(c1))
+			bw.WriteString("</pattern>\n")
+		}
+		bw.WriteString("</defs>\n")
+	}
+
+	for _, shape := range b.shapes {
+		bw.WriteString(shape)
+		bw.WriteString("\n")
+	}
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
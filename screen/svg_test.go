@@ -0,0 +1,265 @@
+package screen
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	var ring []image.Point
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			if x != 1 || y != 1 {
+				ring = append(ring, image.Point{X: x + 10, Y: y + 20})
+			}
+		}
+	}
+
+	expected := "M10 20L13 20L13 23L10 23zM11 21L11 22L12 22L12 21z"
+	if d := (&svgBuffer{}).trace(ring); d != expected {
+		t.Errorf("got %q, expected %q", d, expected)
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	pic := SVGScaler{}.NewPic(image.Rect(0, 0, 320, 190))
+	v := pic.Visual()
+	v.Clear(0xFF)
+	v.Line(0, 50, 319, 50, 0x00)
+	v.Fill(10, 10, 0xF, 0x14)
+	v.Pattern(100, 100, 3, false, true, 0, 0x22)
+	v.Pattern(200, 100, 3, true, false, 7, 0x44)
+	Plot(v, 5, 5, 0x99)
+	Plot(v, 6, 5, 0x99)
+
+	var b bytes.Buffer
+	if err := WriteSVG(&b, pic); err != nil {
+		t.Fatal(err)
+	}
+
+	elements := map[string]int{}
+	d := xml.NewDecoder(&b)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			elements[start.Name.Local]++
+		}
+	}
+
+	expected := map[string]int{"svg": 1, "defs": 1, "pattern": 1, "rect": 2, "line": 0, "circle": 0, "path": 6}
+	for name, n := range expected {
+		if elements[name] != n {
+			t.Errorf("got %d %s elements, expected %d", elements[name], name, n)
+		}
+	}
+}
+
+// rasterizeSVG draws the shapes of an SVG written by WriteSVG, and returns
+// the color of the center of each pixel. Every edge of a shape must be
+// horizontal or vertical, so the center of a pixel stands for all of it.
+func rasterizeSVG(t *testing.T, r io.Reader, bounds image.Rectangle) []string {
+	type edge struct{ x, y0, y1 int }
+
+	var (
+		colors   = make([]string, bounds.Dx()*bounds.Dy())
+		patterns = map[string][2]string{}
+		pattern  string
+	)
+
+	fillPolygon := func(edges []edge, paint string) {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			type crossing struct{ x, dir int }
+			var crossings []crossing
+			for _, e := range edges {
+				switch {
+				case e.y0 <= y && y < e.y1:
+					crossings = append(crossings, crossing{e.x, 1})
+				case e.y1 <= y && y < e.y0:
+					crossings = append(crossings, crossing{e.x, -1})
+				}
+			}
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+			winding := 0
+			for i, c := range crossings {
+				winding += c.dir
+				if winding == 0 || i+1 == len(crossings) {
+					continue
+				}
+				for x := c.x; x < crossings[i+1].x; x++ {
+					if x >= bounds.Min.X && x < bounds.Max.X {
+						colors[(y-bounds.Min.Y)*bounds.Dx()+x-bounds.Min.X] = paint
+					}
+				}
+			}
+		}
+	}
+
+	pathEdges := func(d string) []edge {
+		var (
+			edges        []edge
+			x, y, sx, sy int
+			fields       = strings.FieldsFunc(d, func(r rune) bool { return r == ' ' })
+		)
+		// split the commands from their arguments
+		var tokens []string
+		for _, f := range fields {
+			for len(f) > 0 {
+				i := strings.IndexAny(f[1:], "MLhvz")
+				if i < 0 {
+					tokens = append(tokens, f)
+					break
+				}
+				tokens = append(tokens, f[:i+1])
+				f = f[i+1:]
+			}
+		}
+		num := func(s string) int { return atoi(t, s) }
+		lineTo := func(nx, ny int) {
+			if nx != x && ny != y {
+				t.Fatalf("diagonal edge in path %q", d)
+			}
+			if nx == x && ny != y {
+				edges = append(edges, edge{x, y, ny})
+			}
+			x, y = nx, ny
+		}
+		for i := 0; i < len(tokens); i++ {
+			tok := tokens[i]
+			switch tok[0] {
+			case 'M':
+				x, y = num(tok[1:]), num(tokens[i+1])
+				sx, sy = x, y
+				i++
+			case 'L':
+				lineTo(num(tok[1:]), num(tokens[i+1]))
+				i++
+			case 'h':
+				lineTo(x+num(tok[1:]), y)
+			case 'v':
+				lineTo(x, y+num(tok[1:]))
+			case 'z':
+				lineTo(sx, sy)
+			}
+		}
+		return edges
+	}
+
+	attrs := func(e xml.StartElement) map[string]string {
+		m := map[string]string{}
+		for _, a := range e.Attr {
+			m[a.Name.Local] = a.Value
+		}
+		return m
+	}
+
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if end, ok := tok.(xml.EndElement); ok && end.Name.Local == "pattern" {
+			pattern = ""
+		}
+		e, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		a := attrs(e)
+		switch {
+		case e.Name.Local == "pattern":
+			pattern = a["id"]
+		case pattern != "" && e.Name.Local == "rect":
+			p := patterns[pattern]
+			p[1] = a["fill"]
+			patterns[pattern] = p
+		case pattern != "" && e.Name.Local == "path":
+			p := patterns[pattern]
+			p[0] = a["fill"]
+			patterns[pattern] = p
+		case e.Name.Local == "rect":
+			x, y := atoi(t, a["x"]), atoi(t, a["y"])
+			w, h := atoi(t, a["width"]), atoi(t, a["height"])
+			fillPolygon([]edge{{x, y + h, y}, {x + w, y, y + h}}, a["fill"])
+		case e.Name.Local == "path":
+			fillPolygon(pathEdges(a["d"]), a["fill"])
+		case e.Name.Local == "line" || e.Name.Local == "circle":
+			t.Fatalf("%s elements are not drawn on the pixel grid", e.Name.Local)
+		}
+	}
+
+	// resolve the 2x2 patterns, which have their first color where x and y
+	// are both even or odd
+	for i, c := range colors {
+		if strings.HasPrefix(c, "url(#") {
+			p := patterns[strings.TrimSuffix(strings.TrimPrefix(c, "url(#"), ")")]
+			x, y := i%bounds.Dx(), i/bounds.Dx()
+			colors[i] = p[(x+y)&1]
+		}
+	}
+	return colors
+}
+
+func atoi(t *testing.T, s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestWriteSVGCoversPixels(t *testing.T) {
+	bounds := image.Rect(0, 0, 320, 190)
+	pic := SVGScaler{}.NewPic(bounds)
+	v := pic.Visual()
+	v.Clear(0xFF)
+
+	// diagonal lines, with fills of both solid and dithered colors between
+	// them
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		v.Line(r.Intn(320), r.Intn(190), r.Intn(320), r.Intn(190), uint8(r.Intn(16)))
+	}
+	v.Line(0, 0, 189, 189, 0x00)
+	v.Line(319, 0, 130, 189, 0x11)
+	for i := 0; i < 20; i++ {
+		v.Fill(r.Intn(320), r.Intn(190), 0xF, []uint8{0x22, 0x9a, 0x44, 0x3b}[i%4])
+	}
+	v.Pattern(318, 50, 4, false, true, 0, 0x55)
+	v.Pattern(100, 100, 3, false, true, 0, 0x6e)
+	v.Pattern(200, 100, 7, true, false, 7, 0x44)
+	v.Pattern(50, 188, 2, true, true, 0, 0x77)
+	Plot(v, 5, 5, 0x99)
+
+	var b bytes.Buffer
+	if err := WriteSVG(&b, pic); err != nil {
+		t.Fatal(err)
+	}
+	colors := rasterizeSVG(t, &b, bounds)
+
+	buf := v.(*svgBuffer)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			expected := buf.hex(buf.Pix[buf.PixOffset(x, y)])
+			if c := colors[y*bounds.Dx()+x]; c != expected {
+				t.Fatalf("%d,%d: got %q, expected %s", x, y, c, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package sci
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"golang.org/x/text/encoding"
+
+	"github.com/32bitkid/sci/resource"
+)
+
+// textContext is the context of a message from a text resource.
+func textContext(n resource.Number, i int) string {
+	return fmt.Sprintf("text.%03d:%d", n, i)
+}
+
+// Texts loads every text resource in the Root, by number.
+func (root *Root) Texts() (map[resource.Number]resource.Text, error) {
+	texts := make(map[resource.Number]resource.Text)
+	for _, m := range root.Mapping {
+		if m.Type() != resource.TypeText {
+			continue
+		}
+		res, err := m.Resource()
+		if err != nil {
+			return nil, fmt.Errorf("text %d: %w", m.Number(), err)
+		}
+		text, err := resource.NewText(res.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("text %d: %w", m.Number(), err)
+		}
+		texts[m.Number()] = text
+	}
+	return texts, nil
+}
+
+func sortedNumbers(texts map[resource.Number]resource.Text) []resource.Number {
+	numbers := make([]resource.Number, 0, len(texts))
+	for n := range texts {
+		numbers = append(numbers, n)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
+// TextCatalog collects every string of every text resource in the Root. The
+// strings are converted from the DOS code page enc, such as
+// charmap.CodePage437.
+func (root *Root) TextCatalog(enc encoding.Encoding) (Catalog, error) {
+	texts, err := root.Texts()
+	if err != nil {
+		return nil, err
+	}
+
+	var catalog Catalog
+	for _, n := range sortedNumbers(texts) {
+		strs, err := texts[n].Decode(enc)
+		if err != nil {
+			return nil, fmt.Errorf("text %d: %w", n, err)
+		}
+		for i, s := range strs {
+			catalog = append(catalog, Message{Context: textContext(n, i), Source: s})
+		}
+	}
+	return catalog, nil
+}
+
+// TranslateTexts applies the translations of a catalog to the text resources
+// of the Root, and returns each text resource that changed. Strings without a
+// translation are kept. The translations are converted to the DOS code page
+// enc, and must be representable in it.
+func (root *Root) TranslateTexts(catalog Catalog, enc encoding.Encoding) (map[resource.Number]resource.Text, error) {
+	texts, err := root.Texts()
+	if err != nil {
+		return nil, err
+	}
+
+	translations := catalog.Translations()
+	translated := make(map[resource.Number]resource.Text)
+	for _, n := range sortedNumbers(texts) {
+		strs, err := texts[n].Decode(enc)
+		if err != nil {
+			return nil, fmt.Errorf("text %d: %w", n, err)
+		}
+
+		changed := false
+		for i := range strs {
+			if t, ok := translations[textContext(n, i)]; ok {
+				strs[i], changed = t, true
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		text, err := resource.EncodeText(strs, enc)
+		if err != nil {
+			return nil, fmt.Errorf("text %d: %w", n, err)
+		}
+		translated[n] = text
+	}
+	return translated, nil
+}
+
+// ExportTextPO writes every string of every text resource in the Root as a
+// gettext PO catalog, keyed by resource number and index.
+func (root *Root) ExportTextPO(w io.Writer, enc encoding.Encoding) error {
+	catalog, err := root.TextCatalog(enc)
+	if err != nil {
+		return err
+	}
+	return catalog.WritePO(w)
+}
+
+// ImportTextPO reads a gettext PO catalog that was written by ExportTextPO,
+// and returns each text resource that it translates. The results can be
+// written back with Text.Encode and WritePatch.
+func (root *Root) ImportTextPO(r io.Reader, enc encoding.Encoding) (map[resource.Number]resource.Text, error) {
+	catalog, err := ReadPO(r)
+	if err != nil {
+		return nil, err
+	}
+	return root.TranslateTexts(catalog, enc)
+}
@@ -0,0 +1,240 @@
+package sci
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/32bitkid/sci/resource"
+)
+
+type memoryMapping struct {
+	resourceType resource.Type
+	number       resource.Number
+	payload      []byte
+}
+
+func (m memoryMapping) Type() resource.Type             { return m.resourceType }
+func (m memoryMapping) Number() resource.Number         { return m.number }
+func (m memoryMapping) Stat() (*resource.Header, error) { return &resource.Header{}, nil }
+func (m memoryMapping) Resource() (resource.Resource, error) {
+	return cachedResource{resourceType: m.resourceType, payload: m.payload}, nil
+}
+
+func TestTextPORoundTrip(t *testing.T) {
+	root := Root{Mapping: []resource.Mapping{
+		memoryMapping{resource.TypeText, 2, []byte("Caf\x82\x00Say \"hi\"\nthen\x00")},
+		memoryMapping{resource.TypeText, 1, []byte("Unchanged\x00")},
+	}}
+
+	var po bytes.Buffer
+	if err := root.ExportTextPO(&po, charmap.CodePage437); err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := ReadPO(&po)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Catalog{
+		{Context: "text.001:0", Source: "Unchanged"},
+		{Context: "text.002:0", Source: "Café"},
+		{Context: "text.002:1", Source: "Say \"hi\"\nthen"},
+	}
+	if !reflect.DeepEqual(catalog, expected) {
+		t.Fatalf("got %+v, expected %+v", catalog, expected)
+	}
+
+	catalog[1].Translation = "Café au lait"
+	catalog[2].Translation = "Dites \"salut\""
+	po.Reset()
+	if err := catalog.WritePO(&po); err != nil {
+		t.Fatal(err)
+	}
+
+	texts, err := root.ImportTextPO(&po, charmap.CodePage437)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(texts) != 1 {
+		t.Fatalf("got %d translated texts, expected 1", len(texts))
+	}
+	b, err := texts[2].Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "Caf\x82 au lait\x00Dites \"salut\"\x00" {
+		t.Errorf("got %q", b)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	script := []byte{
+		// code: lofsa "Hi", pushi 100, push1, calle 255 0 4, ret, ret
+		0x02, 0x00, 0x10, 0x00,
+		0x72, 0x0d, 0x00, 0x39, 100, 0x78, 0x47, 0xff, 0x00, 0x04, 0x48, 0x48,
+		// strings
+		0x05, 0x00, 0x0c, 0x00,
+		'H', 'i', 0x00, 'B', 'o', 'b', 0x00, 0x00,
+		// locals, pointing at "Bob"
+		0x0a, 0x00, 0x06, 0x00,
+		0x17, 0x00,
+		// relocation of the local
+		0x08, 0x00, 0x08, 0x00,
+		0x01, 0x00, 0x20, 0x00,
+		0x00, 0x00,
+	}
+	root := Root{Mapping: []resource.Mapping{
+		memoryMapping{resource.TypeScript, 5, script},
+		memoryMapping{resource.TypeText, 100, []byte("Look\x00Door\x00")},
+	}}
+
+	catalog, err := root.LocalizationCatalog(charmap.CodePage437)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Catalog{
+		{Context: "text.100:0", Source: "Look"},
+		{Context: "text.100:1", Source: "Door", Comment: "used by script.005 at 0x000a"},
+		{Context: "script.005@0x0014", Source: "Hi"},
+		{Context: "script.005@0x0017", Source: "Bob", Comment: "not loaded by any code, may be the name of an object"},
+	}
+	if !reflect.DeepEqual(catalog, expected) {
+		t.Fatalf("got %+v, expected %+v", catalog, expected)
+	}
+
+	catalog[1].Translation = "Porte"
+	catalog[2].Translation = "Bonjour"
+	catalog[3].Translation = "Robert"
+	patches, err := root.Localize(catalog, charmap.CodePage437)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 2 {
+		t.Fatalf("got %d patches, expected 2", len(patches))
+	}
+
+	patched, err := resource.NewScript(patches[1].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strs := map[int]string{}
+	for _, block := range patched.Blocks {
+		for _, s := range block.Strings() {
+			strs[s.Offset] = s.Value
+		}
+	}
+
+	instructions, _ := patched.Blocks[0].Instructions()
+	lofsa := instructions[0]
+	if s := strs[lofsa.Offset+lofsa.Size+lofsa.Operands[0]]; s != "Bonjour" {
+		t.Errorf("lofsa loads %q, expected Bonjour", s)
+	}
+	local := int(patched.Blocks[2].Data[0]) | int(patched.Blocks[2].Data[1])<<8
+	if s := strs[local]; s != "Robert" {
+		t.Errorf("local points at %q, expected Robert", s)
+	}
+}
+
+func TestTextRefsTargets(t *testing.T) {
+	script := []byte{
+		0x02, 0x00, 0x20, 0x00,
+		// (Print 100 1): pushi 2, pushi 100, push1, calle 255 0 4
+		0x39, 2, 0x39, 100, 0x78, 0x47, 0xff, 0x00, 0x04,
+		// (Display 100 0): pushi 2, pushi 100, push0, callk Display 4
+		0x39, 2, 0x39, 100, 0x76, 0x43, 0x1b, 0x04,
+		// (proc 100 1) is not printed: pushi 2, pushi 100, push1, callb 3 4
+		0x39, 2, 0x39, 100, 0x78, 0x45, 0x03, 0x04,
+		0x48, 0x48, 0x48,
+		0x00, 0x00,
+	}
+	s, err := resource.NewScript(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := map[resource.Number]resource.Text{100: {"Look", "Door"}}
+	refs := textRefs(5, s, texts)
+	expected := map[string][]textRef{
+		"text.100:1": {{5, 0x09}},
+		"text.100:0": {{5, 0x12}},
+	}
+	if !reflect.DeepEqual(refs, expected) {
+		t.Errorf("got %+v", refs)
+	}
+}
+
+func TestLocalizeSaid(t *testing.T) {
+	vocab := make([]byte, 26*2)
+	for _, w := range []struct {
+		text  string
+		group uint8
+	}{{"at", 0x10}, {"look", 0x20}, {"see", 0x20}, {"tree", 0x30}} {
+		vocab = append(vocab, 0x00)
+		vocab = append(vocab, w.text...)
+		vocab[len(vocab)-1] |= 0x80
+		vocab = append(vocab, 0x00, 0x00, w.group)
+	}
+
+	script := []byte{
+		// code: lofsa said 0x10, lofsa said 0x19, ret, ret
+		0x02, 0x00, 0x0c, 0x00,
+		0x72, 0x09, 0x00, 0x72, 0x0f, 0x00, 0x48, 0x48,
+		// said specs: look<at/tree, see/tree
+		0x04, 0x00, 0x14, 0x00,
+		0x00, 0x20, 0xf8, 0x00, 0x10, 0xf2, 0x00, 0x30, 0xff,
+		0x00, 0x20, 0xf2, 0x00, 0x30, 0xff, 0xff,
+		0x00, 0x00,
+	}
+	root := Root{Mapping: []resource.Mapping{
+		memoryMapping{resource.TypeScript, 5, script},
+		memoryMapping{resource.TypeVocab, 0, vocab},
+	}}
+
+	catalog, err := root.LocalizationCatalog(charmap.CodePage437)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Catalog{
+		{Context: "script.005@0x0010", Source: "look<at/tree", Comment: saidComment},
+		{Context: "script.005@0x0019", Source: "look/tree", Comment: saidComment},
+	}
+	if !reflect.DeepEqual(catalog, expected) {
+		t.Fatalf("got %+v, expected %+v", catalog, expected)
+	}
+
+	// the first fits in place, and the second is moved
+	catalog[0].Translation = "see/tree"
+	catalog[1].Translation = "see<at/tree,tree"
+	patches, err := root.Localize(catalog, charmap.CodePage437)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 1 {
+		t.Fatalf("got %d patches, expected 1", len(patches))
+	}
+	patched, err := resource.NewScript(patches[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _ := resource.NewVocabulary(vocab)
+	saids := map[int]string{}
+	for _, block := range patched.Blocks {
+		for _, said := range block.Saids() {
+			saids[said.Offset], _ = v.DecodeSaid(said.Data)
+		}
+	}
+	instructions, _ := patched.Blocks[0].Instructions()
+	for i, expected := range []string{"look/tree", "look<at/tree,tree"} {
+		lofsa := instructions[i]
+		if s := saids[lofsa.Offset+lofsa.Size+lofsa.Operands[0]]; s != expected {
+			t.Errorf("lofsa %d loads %q, expected %q", i, s, expected)
+		}
+	}
+
+	catalog[0].Translation = "voir/arbre"
+	if _, err := root.Localize(catalog, charmap.CodePage437); err == nil {
+		t.Error("translated a said spec with words that are not in the vocabulary")
+	}
+}